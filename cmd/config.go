@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/kfroemming/ralphkit/internal/paths"
 	"github.com/kfroemming/ralphkit/internal/ui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,15 +15,16 @@ import (
 
 func init() {
 	configCmd.AddCommand(configShowCmd)
+	configSetCmd.Flags().Bool("project", false, "Write to the current repository's .ralphkit/config.yaml instead of the global config")
 	configCmd.AddCommand(configSetCmd)
 	rootCmd.AddCommand(configCmd)
 
 	// Load viper config.
-	home, err := os.UserHomeDir()
+	home, err := paths.Home()
 	if err == nil {
 		viper.SetConfigName("config")
 		viper.SetConfigType("yaml")
-		viper.AddConfigPath(filepath.Join(home, ".ralphkit"))
+		viper.AddConfigPath(home)
 		viper.ReadInConfig()
 	}
 }
@@ -59,11 +61,17 @@ var configSetCmd = &cobra.Command{
 
 		viper.Set(key, value)
 
-		home, err := os.UserHomeDir()
+		configDir, err := paths.Home()
 		if err != nil {
 			return err
 		}
-		configDir := filepath.Join(home, ".ralphkit")
+		if project, _ := cmd.Flags().GetBool("project"); project {
+			cwd, _ := os.Getwd()
+			configDir = paths.ProjectDir(cwd)
+			if configDir == "" {
+				return fmt.Errorf("--project needs to run inside a git repository")
+			}
+		}
 		if err := os.MkdirAll(configDir, 0o755); err != nil {
 			return err
 		}
@@ -101,3 +109,21 @@ func readLines(path string) ([]string, error) {
 	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
 	return lines, nil
 }
+
+// mergeProjectConfig layers .ralphkit/config.yaml of the repository
+// containing dir over the global config, so projects can carry their own
+// hooks and defaults.
+func mergeProjectConfig(dir string) {
+	pd := paths.ProjectDir(dir)
+	if pd == "" {
+		return
+	}
+	f, err := os.Open(filepath.Join(pd, "config.yaml"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if err := viper.MergeConfig(f); err != nil {
+		ui.Warn(fmt.Sprintf("Ignoring %s: %v", f.Name(), err))
+	}
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/kfroemming/ralphkit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+}
+
+var dashboardCmd = &cobra.Command{
+	Use:         "dashboard",
+	Annotations: usesSessions,
+	Short:       "Full-screen dashboard of all sessions",
+	Long: `Show all sessions with live status, iteration, elapsed time, PRD progress and
+last test result, plus the selected session's log.
+
+Keys: ↑/↓ select, p pause/resume, s stop, m message the agent, o open a shell in
+the worktree, q quit.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return tui.RunDashboard()
+	},
+}
@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 
 	"github.com/kfroemming/ralphkit/internal/detect"
+	"github.com/kfroemming/ralphkit/internal/paths"
 	"github.com/spf13/cobra"
 )
 
@@ -47,10 +47,9 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	printCheck("ANTHROPIC_API_KEY", apiKeyOk, false)
 
 	// Config dir
-	home, _ := os.UserHomeDir()
-	configDir := filepath.Join(home, ".ralphkit")
+	configDir, _ := paths.Home()
 	configDirOk := dirExists(configDir)
-	printCheck("~/.ralphkit/ config dir", configDirOk, false)
+	printCheck(configDir+" config dir", configDirOk, false)
 
 	// Project type detection
 	cwd, _ := os.Getwd()
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/kfroemming/ralphkit/internal/paths"
+	"github.com/kfroemming/ralphkit/internal/session"
 	"github.com/kfroemming/ralphkit/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -19,9 +21,10 @@ func init() {
 }
 
 var installCmd = &cobra.Command{
-	Use:   "install",
-	Short: "Check and install dependencies",
-	RunE:  runInstall,
+	Use:         "install",
+	Annotations: usesSessions,
+	Short:       "Check and install dependencies",
+	RunE:        runInstall,
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
@@ -164,17 +167,20 @@ func checkGit() error {
 }
 
 func ensureConfigDir() error {
-	home, err := os.UserHomeDir()
+	configDir, err := paths.Home()
 	if err != nil {
 		return err
 	}
-	configDir := filepath.Join(home, ".ralphkit")
-	sessionsDir := filepath.Join(configDir, "sessions")
+	sessionsDir, err := session.Dir()
+	if err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
 
-	if err := os.MkdirAll(sessionsDir, 0o755); err != nil {
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 	ui.Success(fmt.Sprintf("✓ Config directory: %s", configDir))
+	ui.Success(fmt.Sprintf("✓ Sessions directory: %s", sessionsDir))
 
 	configFile := filepath.Join(configDir, "config.yaml")
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
@@ -2,132 +2,196 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/kfroemming/ralphkit/internal/prd"
+	"github.com/kfroemming/ralphkit/internal/repoctx"
 	"github.com/kfroemming/ralphkit/internal/ui"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
+	newCmd.Flags().String("description", "", "What you are building (1-2 sentences)")
+	newCmd.Flags().String("tech-stack", "", "Tech stack")
+	newCmd.Flags().String("features", "", "Core features, one per line")
+	newCmd.Flags().String("out-of-scope", "", "Out of scope / exclusions")
+	newCmd.Flags().String("success-criteria", "", "How you will know it's done")
+	newCmd.Flags().String("constraints", "", "Constraints (performance, compatibility, style, etc.)")
+	newCmd.Flags().StringP("template", "t", prd.DefaultTemplate, "PRD template, e.g. cli, rest-service, bugfix (see 'ralphkit prd templates')")
+	newCmd.Flags().StringArray("set", nil, "Answer a template question, as key=value (repeatable)")
+	newCmd.Flags().StringP("answers", "a", "", "YAML or JSON file with the answers; - reads stdin")
+	newCmd.Flags().String("file", "", "Where to save the PRD (default: <name>.prd.md)")
+	newCmd.Flags().BoolP("yes", "y", false, "Save the generated PRD without reviewing it")
+	newCmd.Flags().Bool("run", false, "Start a Ralph loop on the PRD once saved")
+	newCmd.Flags().IntP("max-iterations", "n", 0, "With --run, max loop iterations (default from config)")
+	newCmd.Flags().Bool("skip-tests", false, "With --run, skip running tests between iterations")
+	newCmd.Flags().Bool("per-task", false, "With --run, work through the PRD one unchecked task at a time")
+	newCmd.Flags().Bool("tui", false, "With --run, show the live split view")
+	newCmd.Flags().Duration("max-duration", 0, "With --run, time budget for the whole loop, e.g. 2h")
+	newCmd.Flags().StringP("model", "m", "", "Claude model (default from config, shortcuts: opus, sonnet, haiku)")
+	newCmd.Flags().Bool("no-repo-context", false, "Don't describe the current repository to Claude")
+	newCmd.Flags().Bool("refine", false, "Answer Claude's clarifying questions about the first draft before reviewing it")
+	newCmd.Flags().Int("max-questions", prd.DefaultMaxQuestions, "Most clarifying questions Claude may ask about a draft")
 	rootCmd.AddCommand(newCmd)
 }
 
 var newCmd = &cobra.Command{
 	Use:   "new [name]",
 	Short: "Interactive PRD crafting wizard",
-	Long:  "Launch an interactive wizard to build a spec, then optionally run a Ralph loop on it.",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  runNew,
+	Long: `Launch an interactive wizard to build a spec, then optionally run a Ralph loop on it.
+
+--template picks the kind of PRD (CLI tool, REST service, bug fix, ...),
+which decides the questions asked and how the PRD is written. Run
+'ralphkit prd templates' to list them.
+
+Answers can also come from flags, --set key=value, or an answers file
+(YAML or JSON, - for stdin) keyed by question: project_name, description,
+tech_stack, features, out_of_scope, success_criteria, constraints, or the
+template's own keys. List values are turned into bullet points. The wizard
+starts prefilled with those answers, and is skipped entirely once the
+required questions are answered.
+
+Inside a git repository, Claude is also given a summary of it (project type,
+layout, README, tests and recent commits) so the PRD fits the existing code.
+--no-repo-context leaves it out.
+
+When reviewing the draft, [Q]uestions has Claude ask clarifying questions
+about it and revises it with your answers, and [F]eedback revises it from
+freeform change requests such as "split feature 3". Either way the changes
+are shown as a diff. --refine asks the questions before the first review.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runNew,
+}
+
+// answerFlags maps answers file keys to the flags that override them.
+var answerFlags = map[string]string{
+	"description":      "description",
+	"tech_stack":       "tech-stack",
+	"features":         "features",
+	"out_of_scope":     "out-of-scope",
+	"success_criteria": "success-criteria",
+	"constraints":      "constraints",
 }
 
 func runNew(cmd *cobra.Command, args []string) error {
-	name := ""
-	if len(args) > 0 {
-		name = args[0]
-	}
-
-	var (
-		description string
-		techStack   string
-		features    string
-		outOfScope  string
-		successCrit string
-		constraints string
-	)
-
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewInput().
-				Title("Project name").
-				Value(&name).
-				Placeholder("my-project"),
-			huh.NewInput().
-				Title("What are you building? (1-2 sentences)").
-				Value(&description).
-				Placeholder("A CLI tool that..."),
-			huh.NewInput().
-				Title("Tech stack").
-				Value(&techStack).
-				Placeholder("Go, Python, Node, etc."),
-		),
-		huh.NewGroup(
-			huh.NewText().
-				Title("Core features (bullet points)").
-				Value(&features).
-				Placeholder("- Feature one\n- Feature two"),
-			huh.NewText().
-				Title("Out of scope / exclusions").
-				Value(&outOfScope).
-				Placeholder("- Not building X\n- Skipping Y"),
-		),
-		huh.NewGroup(
-			huh.NewText().
-				Title("Success criteria — how will you know it's done?").
-				Value(&successCrit).
-				Placeholder("- All tests pass\n- CLI runs end-to-end"),
-			huh.NewText().
-				Title("Constraints (performance, compatibility, style, etc.)").
-				Value(&constraints).
-				Placeholder("- Must work on macOS and Linux"),
-		),
-	)
-
-	if err := form.Run(); err != nil {
+	templateName, _ := cmd.Flags().GetString("template")
+	tmpl, err := prd.LoadTemplate(templateName)
+	if err != nil {
+		return err
+	}
+	answers, err := answersFromFlags(cmd)
+	if err != nil {
 		return err
 	}
+	answers.Template = tmpl
+	if len(args) > 0 {
+		answers.ProjectName = args[0]
+	}
 
-	if name == "" {
-		name = "project"
+	yes, _ := cmd.Flags().GetBool("yes")
+	interactive := isatty.IsTerminal(os.Stdin.Fd())
+	answersFile, _ := cmd.Flags().GetString("answers")
+	if answersFile == "-" {
+		// Stdin held the answers, so it can't take replies too.
+		interactive = false
+	}
+	if !interactive && !yes {
+		return fmt.Errorf("not running in a terminal; pass --yes to save the PRD without reviewing it")
 	}
+	refine, _ := cmd.Flags().GetBool("refine")
+	if refine && !interactive {
+		return fmt.Errorf("--refine needs a terminal to ask its questions")
+	}
+	maxQuestions, _ := cmd.Flags().GetInt("max-questions")
 
-	answers := prd.Answers{
-		ProjectName: name,
-		Description: description,
-		TechStack:   techStack,
-		Features:    features,
-		OutOfScope:  outOfScope,
-		SuccessCrit: successCrit,
-		Constraints: constraints,
+	if missing := missingAnswers(tmpl, answers); len(missing) > 0 {
+		if !interactive {
+			return fmt.Errorf("the %s template needs answers for %s; pass them as flags, with --set or in an --answers file", tmpl.Name, strings.Join(missing, ", "))
+		}
+		if err := askAnswers(&answers, tmpl); err != nil {
+			return err
+		}
+	}
+	if answers.ProjectName == "" {
+		answers.ProjectName = "project"
 	}
 
+	filename, _ := cmd.Flags().GetString("file")
+	if filename == "" {
+		filename = answers.ProjectName + ".prd.md"
+	}
+	opts := prd.Options{Model: selectModel(cmd), Dir: addRepoContext(cmd, &answers)}
+
 	ui.Header("Generating PRD with Claude...")
 
-	generated, err := prd.Generate(answers)
+	generated, err := prd.Generate(answers, opts)
 	if err != nil {
 		return fmt.Errorf("failed to generate PRD: %w", err)
 	}
 
+	// previous is the draft before the last revision; when set, the review
+	// shows what changed instead of the whole draft.
+	var previous string
+	if refine {
+		previous = generated
+		if generated, err = clarifyDraft(generated, maxQuestions, opts); err != nil {
+			return err
+		}
+	}
+
+	if yes {
+		if err := savePRD(filename, generated); err != nil {
+			return err
+		}
+		if run, _ := cmd.Flags().GetBool("run"); run {
+			return startRun(cmd, filename)
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Println()
-		fmt.Println(generated)
+		if previous != "" {
+			ui.PrintDiff("draft", "revised", previous, generated)
+			previous = ""
+		} else {
+			fmt.Println(generated)
+		}
 		fmt.Println()
 
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("[S]ave / [E]dit / [R]egenerate / [C]ancel: ")
-		choice, _ := reader.ReadString('\n')
+		fmt.Print("[S]ave / [E]dit / [R]egenerate / [Q]uestions / [F]eedback / [C]ancel: ")
+		choice, err := reader.ReadString('\n')
+		if err == io.EOF && choice == "" {
+			choice = "c"
+		}
 		choice = strings.TrimSpace(strings.ToLower(choice))
 
 		switch choice {
 		case "s", "save":
-			filename := name + ".prd.md"
-			if err := os.WriteFile(filename, []byte(generated), 0o644); err != nil {
-				return fmt.Errorf("failed to save PRD: %w", err)
+			if err := savePRD(filename, generated); err != nil {
+				return err
+			}
+			if run, _ := cmd.Flags().GetBool("run"); run {
+				return startRun(cmd, filename)
 			}
-			ui.Success(fmt.Sprintf("Saved to %s", filename))
 			fmt.Print("Run Ralph loop now? [y/N]: ")
 			yn, _ := reader.ReadString('\n')
 			if strings.TrimSpace(strings.ToLower(yn)) == "y" {
-				ui.Header(fmt.Sprintf("Run: ralphkit run %s", filename))
+				return startRun(cmd, filename)
 			}
+			ui.Dim(fmt.Sprintf("Run it later with: ralphkit run %s", filename))
 			return nil
 
 		case "e", "edit":
-			filename := name + ".prd.md"
 			if err := os.WriteFile(filename, []byte(generated), 0o644); err != nil {
 				return fmt.Errorf("failed to write temp file: %w", err)
 			}
@@ -151,17 +215,275 @@ func runNew(cmd *cobra.Command, args []string) error {
 
 		case "r", "regenerate":
 			ui.Header("Regenerating PRD...")
-			generated, err = prd.Generate(answers)
+			generated, err = prd.Generate(answers, opts)
 			if err != nil {
 				return fmt.Errorf("failed to regenerate PRD: %w", err)
 			}
 
+		case "q", "questions":
+			revised, err := clarifyDraft(generated, maxQuestions, opts)
+			if err != nil {
+				return err
+			}
+			previous, generated = generated, revised
+
+		case "f", "feedback":
+			revised, err := reviseDraft(generated, opts)
+			if err != nil {
+				return err
+			}
+			previous, generated = generated, revised
+
 		case "c", "cancel":
 			ui.Warn("Cancelled.")
 			return nil
 
 		default:
-			ui.Warn("Invalid choice. Use S, E, R, or C.")
+			ui.Warn("Invalid choice. Use S, E, R, Q, F, or C.")
+		}
+	}
+}
+
+// askAnswers runs the template's wizard, prefilled with the answers known
+// so far.
+func askAnswers(a *prd.Answers, t *prd.Template) error {
+	values := make([]string, len(t.Questions))
+	fields := make([]huh.Field, 0, len(t.Questions)+1)
+	fields = append(fields, huh.NewInput().
+		Title("Project name").
+		Value(&a.ProjectName).
+		Placeholder("my-project"))
+	for i, q := range t.Questions {
+		values[i] = a.Get(q.Key)
+		title := q.Title
+		// Required questions keep the wizard on their page until answered.
+		validate := func(string) error { return nil }
+		if q.Required {
+			title += " *"
+			validate = func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("an answer is required")
+				}
+				return nil
+			}
+		}
+		if q.Multiline {
+			fields = append(fields, huh.NewText().Title(title).Value(&values[i]).Placeholder(q.Placeholder).Validate(validate))
+		} else {
+			fields = append(fields, huh.NewInput().Title(title).Value(&values[i]).Placeholder(q.Placeholder).Validate(validate))
+		}
+	}
+
+	// Three questions to a page, like the original wizard.
+	var groups []*huh.Group
+	for len(fields) > 0 {
+		n := min(3, len(fields))
+		groups = append(groups, huh.NewGroup(fields[:n]...))
+		fields = fields[n:]
+	}
+	if err := huh.NewForm(groups...).Run(); err != nil {
+		return err
+	}
+	for i, q := range t.Questions {
+		a.Set(q.Key, strings.TrimSpace(values[i]))
+	}
+	return nil
+}
+
+// clarifyDraft has Claude ask questions about a draft and revises it with
+// the user's answers. The draft is returned unchanged if there is nothing to
+// ask or nothing was answered.
+func clarifyDraft(draft string, max int, opts prd.Options) (string, error) {
+	ui.Header("Reviewing the draft...")
+	questions, err := prd.Questions(draft, max, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to review PRD: %w", err)
+	}
+	if len(questions) == 0 {
+		ui.Success("Claude has no questions about this draft.")
+		return draft, nil
+	}
+
+	answers := make([]string, len(questions))
+	var groups []*huh.Group
+	for start := 0; start < len(questions); start += 3 {
+		var fields []huh.Field
+		for i := start; i < min(start+3, len(questions)); i++ {
+			fields = append(fields, huh.NewText().
+				Title(questions[i]).
+				Description("Leave blank to let Claude decide").
+				Value(&answers[i]))
+		}
+		groups = append(groups, huh.NewGroup(fields...))
+	}
+	if err := huh.NewForm(groups...).Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			ui.Warn("Questions skipped.")
+			return draft, nil
+		}
+		return "", err
+	}
+
+	var cs []prd.Clarification
+	for i, q := range questions {
+		if a := strings.TrimSpace(answers[i]); a != "" {
+			cs = append(cs, prd.Clarification{Question: q, Answer: a})
+		}
+	}
+	if len(cs) == 0 {
+		ui.Dim("No answers; keeping the draft.")
+		return draft, nil
+	}
+	ui.Header("Revising PRD...")
+	revised, err := prd.Clarify(draft, cs, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to revise PRD: %w", err)
+	}
+	return revised, nil
+}
+
+// reviseDraft asks for freeform change requests and has Claude apply them
+// to a draft.
+func reviseDraft(draft string, opts prd.Options) (string, error) {
+	var feedback string
+	err := huh.NewText().
+		Title("What should change?").
+		Placeholder("split feature 3, drop analytics").
+		Value(&feedback).
+		Run()
+	if err != nil && !errors.Is(err, huh.ErrUserAborted) {
+		return "", err
+	}
+	if feedback = strings.TrimSpace(feedback); feedback == "" {
+		ui.Dim("No feedback; keeping the draft.")
+		return draft, nil
+	}
+	ui.Header("Revising PRD...")
+	revised, err := prd.Revise(draft, feedback, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to revise PRD: %w", err)
+	}
+	return revised, nil
+}
+
+// missingAnswers lists the required questions of t that have no answer.
+func missingAnswers(t *prd.Template, a prd.Answers) []string {
+	var missing []string
+	for _, q := range t.Questions {
+		if q.Required && a.Get(q.Key) == "" {
+			missing = append(missing, q.Key)
+		}
+	}
+	return missing
+}
+
+// answersFromFlags reads the --answers file, if any, and lays the answer
+// flags over it.
+func answersFromFlags(cmd *cobra.Command) (prd.Answers, error) {
+	v := viper.New()
+	if path, _ := cmd.Flags().GetString("answers"); path != "" {
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return prd.Answers{}, fmt.Errorf("failed to read answers: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		// JSON is valid YAML, so one parser reads both.
+		v.SetConfigType("yaml")
+		if err := v.ReadConfig(r); err != nil {
+			return prd.Answers{}, fmt.Errorf("failed to parse answers: %w", err)
+		}
+	}
+	for key, flag := range answerFlags {
+		if cmd.Flags().Changed(flag) {
+			value, _ := cmd.Flags().GetString(flag)
+			v.Set(key, value)
+		}
+	}
+	sets, _ := cmd.Flags().GetStringArray("set")
+	for _, kv := range sets {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return prd.Answers{}, fmt.Errorf("invalid --set %q (want key=value)", kv)
+		}
+		v.Set(key, value)
+	}
+
+	var a prd.Answers
+	for _, key := range v.AllKeys() {
+		a.Set(key, answerString(v, key))
+	}
+	return a, nil
+}
+
+// answerString returns an answer as text, turning lists into bullet
+// points.
+func answerString(v *viper.Viper, key string) string {
+	switch val := v.Get(key).(type) {
+	case nil:
+		return ""
+	case []any:
+		lines := make([]string, len(val))
+		for i, item := range val {
+			lines[i] = fmt.Sprintf("- %v", item)
+		}
+		return strings.Join(lines, "\n")
+	default:
+		return strings.TrimSpace(fmt.Sprint(val))
+	}
+}
+
+// addRepoContext describes the repository around the working directory in
+// the answers, unless --no-repo-context is set, and returns its root.
+func addRepoContext(cmd *cobra.Command, a *prd.Answers) string {
+	if skip, _ := cmd.Flags().GetBool("no-repo-context"); skip {
+		return ""
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	rc, err := repoctx.Gather(cwd)
+	if err != nil {
+		ui.Warn(fmt.Sprintf("Could not read the repository: %v", err))
+		return ""
+	}
+	if rc == nil {
+		return ""
+	}
+	a.RepoContext = rc.String()
+	ui.Dim(fmt.Sprintf("Including context from %s (%s, %d files)", rc.Root, rc.ProjectType, rc.Files))
+	return rc.Root
+}
+
+func savePRD(filename, content string) error {
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to save PRD: %w", err)
+	}
+	ui.Success(fmt.Sprintf("Saved to %s", filename))
+	return nil
+}
+
+// runFlags are the flags of new that are passed on to the loop with --run.
+var runFlags = []string{"model", "max-iterations", "skip-tests", "per-task", "tui", "max-duration"}
+
+// startRun starts a Ralph loop on the new PRD, as 'ralphkit run' would,
+// using the model chosen for generation.
+func startRun(cmd *cobra.Command, filename string) error {
+	for _, name := range runFlags {
+		if f := cmd.Flags().Lookup(name); f.Changed {
+			if err := runCmd.Flags().Set(name, f.Value.String()); err != nil {
+				return err
+			}
 		}
 	}
+	// runRun reports how the loop ended itself and silences runCmd, but
+	// cobra is executing new.
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	fmt.Println()
+	return runRun(runCmd, []string{filename})
 }
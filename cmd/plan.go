@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kfroemming/ralphkit/internal/paths"
+	"github.com/kfroemming/ralphkit/internal/prd"
+	"github.com/kfroemming/ralphkit/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	planCmd.Flags().StringP("model", "m", "", "Claude model (default from config, shortcuts: opus, sonnet, haiku)")
+	planCmd.Flags().Bool("force", false, "Overwrite an existing plan")
+	planCmd.Flags().StringP("dir", "d", "", "Directory with the code to plan for (default: the repository containing the PRD)")
+	rootCmd.AddCommand(planCmd)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan [prd-file]",
+	Short: "Break a PRD into an ordered task plan with Claude",
+	Long: `Ask Claude to break a PRD into small, ordered steps, each with its
+dependencies and a way to verify it, and write them to <name>.plan.md next
+to the PRD. Run the plan with 'ralphkit run --plan <prd-file>'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prdFile := args[0]
+		data, err := os.ReadFile(prdFile)
+		if err != nil {
+			return fmt.Errorf("failed to read PRD file: %w", err)
+		}
+		planFile := prd.PlanPath(prdFile)
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			if _, err := os.Stat(planFile); err == nil {
+				return fmt.Errorf("%s already exists; pass --force to replace it", planFile)
+			}
+		}
+
+		workDir, _ := cmd.Flags().GetString("dir")
+		if workDir == "" {
+			workDir = filepath.Dir(prdFile)
+			if root := paths.RepoRoot(workDir); root != "" {
+				workDir = root
+			}
+		}
+		if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("work dir %s is not a directory", workDir)
+		}
+
+		model := selectModel(cmd)
+		ui.Header("Planning with Claude...")
+		ui.StatusLine("PRD", prdFile)
+		ui.StatusLine("Model", model)
+		ui.StatusLine("Work dir", workDir)
+		plan, err := prd.GeneratePlan(string(data), prdFile, prd.Options{Model: model, Dir: workDir})
+		if err != nil {
+			return fmt.Errorf("failed to plan: %w", err)
+		}
+		if err := os.WriteFile(planFile, []byte(plan.Markdown()), 0o644); err != nil {
+			return fmt.Errorf("failed to save plan: %w", err)
+		}
+		ui.Success(fmt.Sprintf("Saved %d steps to %s", len(plan.Steps), planFile))
+		ui.Dim(fmt.Sprintf("Run it with: ralphkit run --plan %s", prdFile))
+		return nil
+	},
+}
+
+// planFor returns the plan file to run for prdFile, which may already be
+// a plan.
+func planFor(prdFile string) (string, error) {
+	if strings.HasSuffix(prdFile, ".plan.md") {
+		return prdFile, nil
+	}
+	planFile := prd.PlanPath(prdFile)
+	planInfo, err := os.Stat(planFile)
+	if err != nil {
+		return "", fmt.Errorf("no plan for %s (looked for %s); create one with 'ralphkit plan %s'", prdFile, planFile, prdFile)
+	}
+	if prdInfo, err := os.Stat(prdFile); err == nil && prdInfo.ModTime().After(planInfo.ModTime()) {
+		ui.Warn(fmt.Sprintf("%s changed after %s was made; consider re-running 'ralphkit plan --force %s'", prdFile, planFile, prdFile))
+	}
+	return planFile, nil
+}
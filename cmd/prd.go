@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kfroemming/ralphkit/internal/prd"
+	"github.com/kfroemming/ralphkit/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	prdLintCmd.Flags().IntP("max-iterations", "n", 0, "Iteration limit to check the scope against (default from config)")
+	prdLintCmd.Flags().Bool("strict", false, "Fail on warnings as well as errors")
+	prdLintCmd.Flags().Bool("json", false, "Shorthand for --output json")
+	addOutputFlag(prdLintCmd)
+	prdCmd.AddCommand(prdLintCmd)
+	prdCmd.AddCommand(prdTemplatesCmd)
+	prdImportCmd.Flags().String("from", "", "Input format: github, jira or text (default: detect)")
+	prdImportCmd.Flags().String("file", "", "Where to save the PRD; - prints it (default: <title>.prd.md)")
+	prdImportCmd.Flags().Bool("force", false, "Overwrite an existing file")
+	prdImportCmd.Flags().Bool("expand", false, "Expand the imported spec into a full PRD with Claude")
+	prdImportCmd.Flags().StringP("template", "t", prd.DefaultTemplate, "PRD template to expand with")
+	prdImportCmd.Flags().StringP("model", "m", "", "Claude model for --expand (default from config, shortcuts: opus, sonnet, haiku)")
+	prdImportCmd.Flags().Bool("no-repo-context", false, "Don't describe the current repository to Claude when expanding")
+	prdCmd.AddCommand(prdImportCmd)
+	rootCmd.AddCommand(prdCmd)
+}
+
+var prdCmd = &cobra.Command{
+	Use:   "prd",
+	Short: "Work with PRD/spec files",
+}
+
+var prdLintCmd = &cobra.Command{
+	Use:   "lint [prd-file]",
+	Short: "Check a PRD for problems that keep the loop from finishing",
+	Long: `Check a PRD for problems that keep the loop from finishing.
+
+Rules:
+  no-tasks                    warning  no checklist items to tick off
+  no-acceptance-criteria      warning  checklist items, but none are acceptance criteria
+  feature-without-checkboxes  warning  a feature with nothing to check
+  vague-criterion             warning  a criterion with words like "fast" or "nice" nobody can verify
+  missing-out-of-scope        info     no Out of Scope / Non-Goals section
+  scope-too-large             warning  more open tasks than the iteration limit allows
+  duplicate-task              warning  the same task listed twice
+  duplicate-id                error    two tasks with the same <!-- id: ... -->
+
+Exits 1 if there are errors (or warnings, with --strict).`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOutput, err := outputJSON(cmd)
+		if err != nil {
+			return err
+		}
+		if j, _ := cmd.Flags().GetBool("json"); j {
+			jsonOutput = true
+		}
+		strict, _ := cmd.Flags().GetBool("strict")
+
+		doc, err := prd.ParseFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read PRD file: %w", err)
+		}
+		maxIter, _ := cmd.Flags().GetInt("max-iterations")
+		if maxIter == 0 {
+			maxIter = viper.GetInt("max_iterations")
+		}
+		if maxIter == 0 {
+			maxIter = 10
+		}
+		findings := prd.Lint(doc, prd.LintOptions{MaxIterations: maxIter})
+
+		failed := prd.HasErrors(findings)
+		if strict {
+			for _, f := range findings {
+				failed = failed || f.Severity == prd.SeverityWarning
+			}
+		}
+
+		if jsonOutput {
+			if findings == nil {
+				findings = []prd.Finding{}
+			}
+			if err := printJSON(map[string]any{"file": args[0], "findings": findings, "ok": !failed}); err != nil {
+				return err
+			}
+		} else {
+			printFindings(args[0], findings, true)
+			if len(findings) == 0 {
+				ui.Success(fmt.Sprintf("%s: no problems found", args[0]))
+			}
+		}
+		if failed {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return &exitError{code: 1}
+		}
+		return nil
+	},
+}
+
+// printFindings prints lint findings as file:line: severity rule: message.
+// Info findings are left out unless showInfo is set.
+func printFindings(file string, findings []prd.Finding, showInfo bool) {
+	for _, f := range findings {
+		loc := file
+		if f.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", file, f.Line)
+		}
+		msg := fmt.Sprintf("%s: %s %s: %s", loc, f.Severity, f.Rule, f.Message)
+		switch f.Severity {
+		case prd.SeverityError:
+			ui.Error(msg)
+		case prd.SeverityWarning:
+			ui.Warn(msg)
+		default:
+			if showInfo {
+				ui.Dim(msg)
+			}
+		}
+	}
+}
+
+var prdTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List the PRD templates available to 'ralphkit new --template'",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templates, err := prd.Templates()
+		if err != nil {
+			return err
+		}
+		for _, t := range templates {
+			fmt.Printf("%-15s %-20s %s\n", t.Name, t.Title, t.Description)
+			if t.Path != "" {
+				ui.Dim(fmt.Sprintf("%-15s from %s", "", t.Path))
+			}
+		}
+		if dir, err := prd.TemplateDir(); err == nil {
+			fmt.Println()
+			ui.Dim(fmt.Sprintf("Add your own as YAML files in %s", dir))
+		}
+		return nil
+	},
+}
+
+var prdImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Convert a GitHub issue, Jira export or text file into a PRD",
+	Long: `Convert a spec from an issue tracker export or a text file into a ralphkit PRD.
+
+Supported inputs, read from a local file (- for stdin):
+  github  gh issue view <n> --json number,title,body,url,labels
+  jira    a single issue exported as XML, or JSON from the REST API
+          (add ?expand=names so custom fields such as Acceptance Criteria
+          are recognised)
+  text    freeform text or markdown; the first line is the title
+
+Task lists become checkboxes. Acceptance criteria, from a heading in the
+body or a Jira field, become the Acceptance Criteria checklist. With
+--expand, the result is sent through the same generation as 'ralphkit new'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+		from, _ := cmd.Flags().GetString("from")
+		im, err := prd.Import(data, from)
+		if err != nil {
+			return err
+		}
+
+		content := im.Markdown()
+		if expand, _ := cmd.Flags().GetBool("expand"); expand {
+			templateName, _ := cmd.Flags().GetString("template")
+			tmpl, err := prd.LoadTemplate(templateName)
+			if err != nil {
+				return err
+			}
+			answers := im.Answers()
+			answers.Template = tmpl
+			opts := prd.Options{Model: selectModel(cmd), Dir: addRepoContext(cmd, &answers)}
+			ui.Header("Expanding with Claude...")
+			if content, err = prd.Generate(answers, opts); err != nil {
+				return fmt.Errorf("failed to expand PRD: %w", err)
+			}
+		}
+
+		output, _ := cmd.Flags().GetString("file")
+		if output == "-" {
+			fmt.Print(content)
+			return nil
+		}
+		if output == "" {
+			output = im.FileName()
+		}
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			if _, err := os.Stat(output); err == nil {
+				return fmt.Errorf("%s already exists; pass --force to replace it", output)
+			}
+		}
+		if err := os.WriteFile(output, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to save PRD: %w", err)
+		}
+		doc := prd.Parse(content)
+		ui.Success(fmt.Sprintf("Saved to %s (%d checklist items, %d acceptance criteria)", output, len(doc.Tasks), len(doc.AcceptanceCriteria())))
+		printFindings(output, prd.Lint(doc, prd.LintOptions{}), false)
+		return nil
+	},
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kfroemming/ralphkit/internal/report"
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	reportCmd.Flags().String("junit", "", "Write JUnit XML to this file (- for stdout)")
+	reportCmd.Flags().String("html", "", "Write the HTML report to this file (- for stdout)")
+	reportCmd.Flags().String("junit-cases", report.CasesIteration, "JUnit test cases per iteration or per PRD checklist item (iteration, criteria)")
+	rootCmd.AddCommand(reportCmd)
+}
+
+var reportCmd = &cobra.Command{
+	Use:         "report [session]",
+	Annotations: usesSessions,
+	Short:       "Write JUnit XML and HTML reports for a session",
+	Long: `Write reports for a session from its state, event log and log file.
+
+The JUnit XML has one test case per iteration (failing when the iteration's
+tests failed) or, with --junit-cases criteria, per PRD checklist item. The
+HTML report is a single self-contained page with a timeline, test trend,
+per-iteration diffs and the final PRD checklist.
+
+Without --junit or --html, both are written to <session>-junit.xml and
+<session>-report.html in the current directory.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReport,
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	junitPath, _ := cmd.Flags().GetString("junit")
+	htmlPath, _ := cmd.Flags().GetString("html")
+	cases, _ := cmd.Flags().GetString("junit-cases")
+	if cases != report.CasesIteration && cases != report.CasesCriteria {
+		return fmt.Errorf("invalid --junit-cases %q (want iteration or criteria)", cases)
+	}
+
+	s, err := session.Load(args[0])
+	if err != nil {
+		return err
+	}
+	r, err := report.Build(s)
+	if err != nil {
+		return fmt.Errorf("failed to build report: %w", err)
+	}
+
+	if junitPath == "" && htmlPath == "" {
+		junitPath = s.Name + "-junit.xml"
+		htmlPath = s.Name + "-report.html"
+	}
+	if junitPath != "" {
+		if err := writeReport(junitPath, func(w io.Writer) error { return r.WriteJUnit(w, cases) }); err != nil {
+			return fmt.Errorf("failed to write JUnit report: %w", err)
+		}
+	}
+	if htmlPath != "" {
+		if err := writeReport(htmlPath, r.WriteHTML); err != nil {
+			return fmt.Errorf("failed to write HTML report: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeReport(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	ui.Success(fmt.Sprintf("Wrote %s", path))
+	return nil
+}
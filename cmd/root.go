@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -24,8 +25,15 @@ Each iteration: agent reads spec -> works on tasks -> evaluates completion -> lo
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		ui.Error(err.Error())
-		os.Exit(1)
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code = exit.code
+		}
+		if msg := err.Error(); msg != "" {
+			ui.Error(msg)
+		}
+		os.Exit(code)
 	}
 }
 
@@ -44,5 +52,14 @@ func init() {
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		q, _ := cmd.Flags().GetBool("quiet")
 		ui.Quiet = q
+		cwd, _ := os.Getwd()
+		// run reads the config of the repository it works in, which
+		// --dir may make a different one.
+		if cmd != runCmd {
+			mergeProjectConfig(cwd)
+		}
+		if needsSessions(cmd) {
+			configureSessionStore(cwd)
+		}
 	}
 }
@@ -4,15 +4,20 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/kfroemming/ralphkit/internal/loop"
+	"github.com/kfroemming/ralphkit/internal/notify"
+	"github.com/kfroemming/ralphkit/internal/prd"
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/tui"
 	"github.com/kfroemming/ralphkit/internal/ui"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -24,35 +29,92 @@ func init() {
 	runCmd.Flags().IntP("max-iterations", "n", 0, "Max loop iterations (default from config)")
 	runCmd.Flags().StringP("worktree", "w", "", "Git worktree path to run in")
 	runCmd.Flags().StringP("session-name", "s", "", "Name this session (auto-generated if not provided)")
+	runCmd.Flags().Bool("force", false, "Reuse the name of a finished session, archiving its previous state and log")
 	runCmd.Flags().StringP("dir", "d", "", "Working directory (default: current dir)")
-	runCmd.Flags().Bool("notify", false, "Send macOS notification on completion")
+	runCmd.Flags().Bool("notify", false, "Show a desktop notification when the loop finishes or stalls")
 	runCmd.Flags().Bool("dangerously-skip-permissions", false, "Pass --dangerously-skip-permissions to claude")
 	runCmd.Flags().Bool("dry-run", false, "Print resolved config and prompt without running Claude")
+	runCmd.Flags().Bool("tui", false, "Show a live split view of agent output, tests and progress (needs a terminal)")
+	runCmd.Flags().Duration("max-duration", 0, "Time budget for the whole loop, e.g. 2h (default: no limit)")
+	runCmd.Flags().Bool("stop-on-stall", false, "End the loop once it makes no PRD progress for stall_iterations iterations")
+	runCmd.Flags().Bool("no-lint", false, "Don't lint the PRD before starting")
+	runCmd.Flags().Bool("plan", false, "Work through the PRD's plan (<name>.plan.md, see 'ralphkit plan') instead of the PRD itself")
+	runCmd.Flags().Bool("per-task", false, "Work through the PRD one unchecked task at a time, ticking each off once tests pass")
+	runCmd.Flags().Int("task-max-attempts", 0, "With --per-task, iterations a task gets before it is skipped as failed (default: task_max_attempts, or 3)")
+	addOutputFlag(runCmd)
 	rootCmd.AddCommand(runCmd)
 }
 
 var runCmd = &cobra.Command{
 	Use:   "run [prd-file]",
 	Short: "Start a Ralph loop from a PRD/spec file",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runRun,
+	Long: `Start a Ralph loop from a PRD/spec file.
+
+In a git repository with at least one commit, the working tree, including
+untracked files that aren't ignored, is snapshotted after each agent run
+for the per-iteration diffs of 'ralphkit report'. The snapshots are stored
+as unreferenced objects in the repository's .git directory, which 'git gc'
+prunes after about two weeks.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRun,
 }
 
-func runRun(cmd *cobra.Command, args []string) error {
+func runRun(cmd *cobra.Command, args []string) (err error) {
+	jsonOutput, err := outputJSON(cmd)
+	if err != nil {
+		return err
+	}
+	// With --output json, stdout carries exactly one result document, so
+	// failures before the loop starts are reported there too.
+	var sessionName string
+	reported := false
+	if jsonOutput {
+		defer func() {
+			if err == nil || reported {
+				return
+			}
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			if perr := printJSON(newRunResult(sessionName, nil, exitFailed, err)); perr != nil {
+				err = perr
+			}
+		}()
+	}
+
 	prdFile := args[0]
+	usePlan, _ := cmd.Flags().GetBool("plan")
+	if usePlan {
+		var err error
+		if prdFile, err = planFor(prdFile); err != nil {
+			return err
+		}
+	}
 	data, err := os.ReadFile(prdFile)
 	if err != nil {
 		return fmt.Errorf("failed to read PRD file: %w", err)
 	}
 
-	model, _ := cmd.Flags().GetString("model")
-	if model == "" {
-		model = viper.GetString("default_model")
+	prdPath, _ := filepath.Abs(prdFile)
+
+	workDir, _ := cmd.Flags().GetString("dir")
+	if workDir == "" {
+		worktree, _ := cmd.Flags().GetString("worktree")
+		if worktree != "" {
+			workDir = worktree
+		} else {
+			workDir, _ = os.Getwd()
+		}
 	}
-	if model == "" {
-		model = "claude-opus-4-6"
+	workDir, _ = filepath.Abs(workDir)
+	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("work dir %s is not a directory", workDir)
 	}
-	model = resolveModel(model)
+	// Only the config of the repository being worked on applies; its hooks
+	// and notify_command run as shell commands.
+	mergeProjectConfig(workDir)
+	configureSessionStore(workDir)
+
+	model := selectModel(cmd)
 
 	maxIter, _ := cmd.Flags().GetInt("max-iterations")
 	if maxIter == 0 {
@@ -62,30 +124,43 @@ func runRun(cmd *cobra.Command, args []string) error {
 		maxIter = 10
 	}
 
-	skipTests, _ := cmd.Flags().GetBool("skip-tests")
-	dangerouslySkip, _ := cmd.Flags().GetBool("dangerously-skip-permissions")
-	notify, _ := cmd.Flags().GetBool("notify")
-
-	workDir, _ := cmd.Flags().GetString("dir")
-	if workDir == "" {
-		worktree, _ := cmd.Flags().GetString("worktree")
-		if worktree != "" {
-			workDir = worktree
-		} else {
-			workDir, _ = os.Getwd()
+	if noLint, _ := cmd.Flags().GetBool("no-lint"); !noLint {
+		findings := prd.Lint(prd.Parse(string(data)), prd.LintOptions{MaxIterations: maxIter})
+		printFindings(prdFile, findings, false)
+		if prd.HasErrors(findings) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return fmt.Errorf("%s has lint errors; fix them or pass --no-lint (see 'ralphkit prd lint %s')", prdFile, prdFile)
 		}
 	}
-	workDir, _ = filepath.Abs(workDir)
 
-	sessionName, _ := cmd.Flags().GetString("session-name")
+	skipTests, _ := cmd.Flags().GetBool("skip-tests")
+	dangerouslySkip, _ := cmd.Flags().GetBool("dangerously-skip-permissions")
+	desktopNotify, _ := cmd.Flags().GetBool("notify")
+
+	sessionName, _ = cmd.Flags().GetString("session-name")
 	if sessionName == "" {
 		base := strings.TrimSuffix(filepath.Base(prdFile), filepath.Ext(prdFile))
-		sessionName = fmt.Sprintf("%s-%d", base, time.Now().Unix())
+		sessionName = fmt.Sprintf("%s-%d", session.SanitizeName(base), time.Now().Unix())
+	}
+	if err := session.ValidateName(sessionName); err != nil {
+		return err
 	}
+	force, _ := cmd.Flags().GetBool("force")
 
 	quiet, _ := cmd.Flags().GetBool("quiet")
 
+	maxDuration, _ := cmd.Flags().GetDuration("max-duration")
+	stopOnStall, _ := cmd.Flags().GetBool("stop-on-stall")
+	perTask, _ := cmd.Flags().GetBool("per-task")
+	if perTask && len(prd.Parse(string(data)).Tasks) == 0 {
+		return fmt.Errorf("--per-task needs a PRD with checklist items (- [ ] ...)")
+	}
+
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if dryRun && jsonOutput {
+		return fmt.Errorf("--dry-run prints text; it can't be combined with --output json")
+	}
 	if dryRun {
 		fmt.Println("--- DRY RUN MODE ---")
 		fmt.Println()
@@ -105,20 +180,45 @@ func runRun(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 		fmt.Println("Prompt that would be sent to Claude (iteration 1):")
 		fmt.Println("---")
-		fmt.Println(loop.BuildPrompt(string(data), "", 1))
+		if remaining := prd.Parse(string(data)).Remaining(); perTask && len(remaining) > 0 {
+			fmt.Println(loop.BuildTaskPrompt(string(data), remaining[0], "", "", nil, 1))
+		} else {
+			fmt.Println(loop.BuildPrompt(string(data), "", "", nil, 1))
+		}
 		fmt.Println("---")
 		fmt.Println()
 		fmt.Println("(dry-run complete — no Claude invocation performed)")
 		return nil
 	}
+	applyRetention()
+
+	if perTask || usePlan {
+		// Tasks are tracked by ID, so pin each one with an ID comment before
+		// the agent gets a chance to reword it.
+		doc := prd.Parse(string(data))
+		if n := doc.AssignIDs(); n > 0 {
+			if err := doc.WriteFile(prdPath); err != nil {
+				return fmt.Errorf("failed to add task IDs to %s: %w", prdFile, err)
+			}
+			data = []byte(doc.String())
+			ui.Dim(fmt.Sprintf("Added ID comments to %d task(s) in %s so they can be tracked.", n, prdFile))
+		}
+	}
 
+	if jsonOutput {
+		// Keep stdout for the result; agent output still goes to the log.
+		ui.Silent = true
+		quiet = true
+	}
 	ui.Header("Ralph Loop")
 	ui.StatusLine("PRD", prdFile)
 	ui.StatusLine("Model", model)
 	ui.StatusLine("Max iterations", fmt.Sprintf("%d", maxIter))
 	ui.StatusLine("Work dir", workDir)
 	ui.StatusLine("Session", sessionName)
-	fmt.Println()
+	if !jsonOutput {
+		fmt.Println()
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -132,20 +232,196 @@ func runRun(cmd *cobra.Command, args []string) error {
 
 	cfg := loop.Config{
 		PRDContent:                 string(data),
-		Model:                     model,
-		MaxIterations:             maxIter,
-		SkipTests:                 skipTests,
-		WorkDir:                   workDir,
-		SessionName:               sessionName,
+		PRDFile:                    prdPath,
+		Model:                      model,
+		MaxIterations:              maxIter,
+		SkipTests:                  skipTests,
+		WorkDir:                    workDir,
+		SessionName:                sessionName,
 		DangerouslySkipPermissions: dangerouslySkip,
-		Quiet:                     quiet,
+		Quiet:                      quiet,
+		Force:                      force,
+		StallIterations:            stallIterations(),
+		StopOnStall:                stopOnStall,
+		MaxDuration:                maxDuration,
+		PerTask:                    perTask,
+		TaskMaxAttempts:            taskMaxAttempts(cmd),
+	}
+	cfg.Hooks, err = hooksFromConfig()
+	if err != nil {
+		return err
+	}
+	notifier, err := notifierFromConfig(desktopNotify)
+	if err != nil {
+		return err
+	}
+	cfg.Notifier = notifier
+
+	useTUI, _ := cmd.Flags().GetBool("tui")
+	if useTUI && (jsonOutput || !(isatty.IsTerminal(os.Stdout.Fd()) && isatty.IsTerminal(os.Stdin.Fd()))) {
+		ui.Dim("Not a terminal; falling back to plain output.")
+		useTUI = false
+	}
+	// From here on the outcome is reported through the exit code and the
+	// error, not cobra's usage text.
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	var state *session.State
+	if useTUI {
+		state, err = runLoopTUI(ctx, cancel, cfg)
+	} else {
+		state, err = loop.Run(ctx, cfg)
+	}
+	ui.Silent = false
+	if err != nil {
+		e := notify.Event{Kind: notify.Failed, Session: sessionName, MaxIterations: maxIter, WorkDir: workDir, Error: err.Error()}
+		if state != nil {
+			e = notify.ForSession(state, notify.Failed)
+			e.Error = err.Error()
+		}
+		if nerr := notifier.Notify(context.Background(), e); nerr != nil {
+			ui.Warn(fmt.Sprintf("Notification failed: %v", nerr))
+		}
+	}
+
+	code := exitCodeFor(state, err)
+	if jsonOutput {
+		reported = true
+		if perr := printJSON(newRunResult(sessionName, state, code, err)); perr != nil {
+			return perr
+		}
+	}
+	if code != 0 {
+		return &exitError{code: code, err: err}
+	}
+	return nil
+}
+
+// Exit codes of ralphkit run, so CI jobs can branch on the outcome.
+const (
+	exitComplete      = 0
+	exitFailed        = 1
+	exitMaxIterations = 2
+	exitBudget        = 3
+	exitStalled       = 4
+	exitInterrupted   = 5
+	exitAgentError    = 6
+	exitTasksFailed   = 7
+)
+
+func exitCodeFor(s *session.State, err error) int {
+	if s == nil {
+		return exitFailed
+	}
+	switch s.Reason {
+	case session.ReasonComplete:
+		return exitComplete
+	case session.ReasonMaxIterations:
+		return exitMaxIterations
+	case session.ReasonBudget:
+		return exitBudget
+	case session.ReasonStalled:
+		return exitStalled
+	case session.ReasonInterrupted, session.ReasonStopRequested:
+		return exitInterrupted
+	case session.ReasonAgentError:
+		return exitAgentError
+	case session.ReasonTasksFailed:
+		return exitTasksFailed
+	}
+	if err != nil {
+		return exitFailed
+	}
+	return exitComplete
+}
+
+// runResult is the --output json result of a run.
+type runResult struct {
+	Session         string  `json:"session"`
+	Status          string  `json:"status"`
+	Reason          string  `json:"reason,omitempty"`
+	ExitCode        int     `json:"exitCode"`
+	Iterations      int     `json:"iterations"`
+	MaxIterations   int     `json:"maxIterations"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	TasksDone       int     `json:"tasksDone"`
+	TasksTotal      int     `json:"tasksTotal"`
+	Tests           string  `json:"tests,omitempty"`
+	WorkDir         string  `json:"workDir,omitempty"`
+	LogFile         string  `json:"logFile,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	// Tasks are the per-task outcomes with --per-task.
+	Tasks []*session.TaskRun `json:"tasks,omitempty"`
+}
+
+func newRunResult(name string, s *session.State, code int, err error) runResult {
+	// Without a state the loop never started.
+	r := runResult{Session: name, Status: "error", ExitCode: code}
+	if s != nil {
+		r.Session = s.Name
+		r.Status = s.Status
+		r.Reason = s.Reason
+		r.Iterations = s.Iterations
+		r.MaxIterations = s.MaxIterations
+		r.DurationSeconds = time.Since(s.StartTime).Seconds()
+		if s.EndTime != nil {
+			r.DurationSeconds = s.EndTime.Sub(s.StartTime).Seconds()
+		}
+		r.TasksDone = s.TasksDone
+		r.TasksTotal = s.TasksTotal
+		r.Tests = s.LastTests
+		r.WorkDir = s.WorkDir
+		r.LogFile = s.LogFile
+		r.Tasks = s.Tasks
 	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
 
-	err = loop.Run(ctx, cfg)
-	if notify {
-		sendNotification(err)
+// runLoopTUI runs the loop behind the full-screen run view, then prints a
+// plain summary once the terminal is handed back.
+func runLoopTUI(ctx context.Context, cancel context.CancelFunc, cfg loop.Config) (*session.State, error) {
+	ui.Silent = true
+	// The run view shows the agent's output itself.
+	cfg.Quiet = true
+	s, err := tui.RunLoop(cancel, func(onStart func(string)) error {
+		cfg.OnStart = onStart
+		_, err := loop.Run(ctx, cfg)
+		return err
+	})
+	ui.Silent = false
+	if s == nil {
+		return nil, err
+	}
+	elapsed := time.Since(s.StartTime)
+	if s.EndTime != nil {
+		elapsed = s.EndTime.Sub(s.StartTime)
+	}
+	switch s.Reason {
+	case session.ReasonComplete:
+		ui.Celebration(s.Iterations, elapsed)
+	case session.ReasonMaxIterations:
+		ui.MaxIterationsWarning(s.MaxIterations)
+	default:
+		ui.Warn(fmt.Sprintf("Session %s %s (%s) after %d iteration(s).", s.Name, s.Status, s.Reason, s.Iterations))
+	}
+	return s, err
+}
+
+// selectModel picks the Claude model from --model, then default_model,
+// then the built-in default.
+func selectModel(cmd *cobra.Command) string {
+	model, _ := cmd.Flags().GetString("model")
+	if model == "" {
+		model = viper.GetString("default_model")
+	}
+	if model == "" {
+		model = "claude-opus-4-6"
 	}
-	return err
+	return resolveModel(model)
 }
 
 func resolveModel(m string) string {
@@ -161,11 +437,99 @@ func resolveModel(m string) string {
 	}
 }
 
-func sendNotification(err error) {
-	msg := "Ralph loop complete!"
-	if err != nil {
-		msg = fmt.Sprintf("Ralph loop failed: %v", err)
+// defaultStallIterations is used when stall_iterations is not configured.
+const defaultStallIterations = 3
+
+func stallIterations() int {
+	if viper.IsSet("stall_iterations") {
+		return viper.GetInt("stall_iterations")
+	}
+	return defaultStallIterations
+}
+
+// defaultTaskMaxAttempts is used when neither --task-max-attempts nor
+// task_max_attempts is set.
+const defaultTaskMaxAttempts = 3
+
+func taskMaxAttempts(cmd *cobra.Command) int {
+	if n, _ := cmd.Flags().GetInt("task-max-attempts"); n > 0 {
+		return n
+	}
+	if viper.IsSet("task_max_attempts") {
+		return viper.GetInt("task_max_attempts")
+	}
+	return defaultTaskMaxAttempts
+}
+
+// notifierFromConfig builds the notification sinks from the notify_* config
+// keys. desktop forces desktop notifications on, as --notify does.
+func notifierFromConfig(desktop bool) (*notify.Multi, error) {
+	m := &notify.Multi{}
+	if on := viper.GetString("notify_on"); on != "" {
+		for _, kind := range strings.Split(on, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(notify.Kinds, kind) {
+				return nil, fmt.Errorf("invalid notify_on event %q (want %s)", kind, strings.Join(notify.Kinds, ", "))
+			}
+			m.On = append(m.On, kind)
+		}
+	}
+	if desktop || viper.GetBool("notify_desktop") {
+		m.Add(notify.Desktop{})
+	}
+	if url := viper.GetString("notify_webhook"); url != "" {
+		format := viper.GetString("notify_webhook_format")
+		if format == "" {
+			format = notify.WebhookFormat(url)
+		}
+		m.Add(notify.Webhook{URL: url, Format: format})
+	}
+	if command := viper.GetString("notify_command"); command != "" {
+		m.Add(notify.Command{Command: command})
+	}
+	return m, nil
+}
+
+// hooksFromConfig reads hook_<name> commands, with optional
+// hook_<name>_timeout and hook_<name>_policy overrides of the hook_timeout
+// and hook_failure_policy defaults.
+func hooksFromConfig() (map[string]loop.Hook, error) {
+	defaultTimeout := loop.DefaultHookTimeout
+	if v := viper.GetString("hook_timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook_timeout: %w", err)
+		}
+		defaultTimeout = d
+	}
+	defaultPolicy := viper.GetString("hook_failure_policy")
+	if defaultPolicy == "" {
+		defaultPolicy = loop.HookReport
+	}
+
+	hooks := map[string]loop.Hook{}
+	for _, name := range loop.HookNames {
+		command := viper.GetString("hook_" + name)
+		if command == "" {
+			continue
+		}
+		hook := loop.Hook{Command: command, Timeout: defaultTimeout, Policy: defaultPolicy}
+		if v := viper.GetString("hook_" + name + "_timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hook_%s_timeout: %w", name, err)
+			}
+			hook.Timeout = d
+		}
+		if v := viper.GetString("hook_" + name + "_policy"); v != "" {
+			hook.Policy = v
+		}
+		switch hook.Policy {
+		case loop.HookAbort, loop.HookReport, loop.HookIgnore:
+		default:
+			return nil, fmt.Errorf("invalid failure policy %q for %s hook (want abort, report or ignore)", hook.Policy, name)
+		}
+		hooks[name] = hook
 	}
-	script := fmt.Sprintf(`display notification "%s" with title "ralphkit"`, msg)
-	exec.Command("osascript", "-e", script).Run()
+	return hooks, nil
 }
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/kfroemming/ralphkit/internal/server"
+	"github.com/kfroemming/ralphkit/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Require this token on every request (default: $RALPHKIT_TOKEN); mandatory off localhost")
+	rootCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:         "serve",
+	Annotations: usesSessions,
+	Short:       "Serve a web dashboard, HTTP API and metrics for sessions",
+	Long: `Start a local HTTP server with a web dashboard and a JSON API over sessions:
+
+  GET /api/sessions              list sessions
+  GET /api/sessions/{name}       show one session
+  GET /api/sessions/{name}/events the session's event log
+  GET /api/sessions/{name}/log   stream the log as server-sent events
+  GET /metrics                   Prometheus metrics for all sessions
+
+The server binds to localhost by default. Binding to any other interface
+requires a token, passed as "Authorization: Bearer <token>" or ?token=<token>.
+Without a token, requests must be addressed to localhost or a loopback IP;
+others are refused, which blocks DNS rebinding attacks from web pages.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	token, _ := cmd.Flags().GetString("token")
+	if token == "" {
+		token = os.Getenv("RALPHKIT_TOKEN")
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --addr %q: %w", addr, err)
+	}
+	if !server.IsLoopback(host) && token == "" {
+		return fmt.Errorf("refusing to serve on %s without --token; bind to 127.0.0.1 or set a token", addr)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	url := "http://" + ln.Addr().String() + "/"
+	if token != "" {
+		url += "?token=" + token
+	}
+	ui.Header("ralphkit serve")
+	ui.StatusLine("Dashboard", url)
+	ui.Dim("Press Ctrl+C to stop.")
+	return http.Serve(ln, server.Handler(token))
+}
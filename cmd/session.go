@@ -2,38 +2,78 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/kfroemming/ralphkit/internal/paths"
 	"github.com/kfroemming/ralphkit/internal/session"
 	"github.com/kfroemming/ralphkit/internal/ui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
+	addOutputFlag(sessionListCmd)
+	sessionListCmd.Flags().StringSlice("status", nil, "Only list sessions with these statuses ("+strings.Join(session.Statuses, ", ")+")")
 	sessionCmd.AddCommand(sessionListCmd)
+	addOutputFlag(sessionShowCmd)
+	sessionCmd.AddCommand(sessionShowCmd)
 	sessionCmd.AddCommand(sessionStopCmd)
+	sessionCleanCmd.Flags().String("older-than", "", "Only clean sessions that finished at least this long ago (e.g. 12h, 7d, 2w)")
+	sessionCleanCmd.Flags().StringSlice("status", nil, "Only clean sessions with these statuses (complete, stopped, crashed)")
+	sessionCleanCmd.Flags().Int("keep-last", 0, "Keep the N most recent matching sessions")
+	sessionCleanCmd.Flags().Bool("archive", false, "Compress sessions into the archive directory next to the sessions directory (default ~/.ralphkit/archive/) instead of deleting them")
+	sessionCleanCmd.Flags().Bool("dry-run", false, "Show what would be cleaned without removing anything")
 	sessionCmd.AddCommand(sessionCleanCmd)
+	sessionCmd.AddCommand(sessionSayCmd)
+	sessionCmd.AddCommand(sessionPauseCmd)
+	sessionCmd.AddCommand(sessionResumeCmd)
 	rootCmd.AddCommand(sessionCmd)
 }
 
 var sessionCmd = &cobra.Command{
-	Use:   "session",
-	Short: "Manage Ralph sessions",
+	Use:         "session",
+	Annotations: usesSessions,
+	Short:       "Manage Ralph sessions",
 }
 
 var sessionListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all sessions",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOutput, err := outputJSON(cmd)
+		if err != nil {
+			return err
+		}
+		statuses, _ := cmd.Flags().GetStringSlice("status")
+		if err := checkStatuses(statuses, session.Statuses); err != nil {
+			return err
+		}
 		sessions, err := session.List()
 		if err != nil {
 			return err
 		}
+		if len(statuses) > 0 {
+			sessions = slices.DeleteFunc(sessions, func(s *session.State) bool {
+				return !slices.Contains(statuses, s.DisplayStatus())
+			})
+		}
+		if jsonOutput {
+			if sessions == nil {
+				sessions = []*session.State{}
+			}
+			return printJSON(sessions)
+		}
 		if len(sessions) == 0 {
 			ui.Dim("No sessions found.")
 			return nil
 		}
 		for _, s := range sessions {
-			status := s.Status
+			status := s.DisplayStatus()
 			switch status {
 			case "running":
 				status = ui.FormatStatus("running")
@@ -41,13 +81,22 @@ var sessionListCmd = &cobra.Command{
 				status = ui.FormatStatus("complete")
 			case "stopped":
 				status = ui.FormatStatus("stopped")
+			case "crashed":
+				status = ui.FormatStatus("crashed")
+			case "paused":
+				status = ui.FormatStatus("paused")
+			}
+			store := "global"
+			if s.Dir == projectSessionsDir {
+				store = "project"
 			}
-			fmt.Printf("%-20s  %s  iter %d/%d  %s  %s\n",
+			fmt.Printf("%-20s  %s  iter %d/%d  %s  %-7s  %s\n",
 				s.Name,
 				status,
 				s.Iterations,
 				s.MaxIterations,
 				s.StartTime.Format("2006-01-02 15:04"),
+				store,
 				s.WorkDir,
 			)
 		}
@@ -55,6 +104,67 @@ var sessionListCmd = &cobra.Command{
 	},
 }
 
+var sessionShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show details of a session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOutput, err := outputJSON(cmd)
+		if err != nil {
+			return err
+		}
+		s, err := session.Load(args[0])
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON(s)
+		}
+		status := s.Status
+		if status == "running" && s.Paused {
+			status = "paused"
+		}
+		ui.Header("Session " + s.Name)
+		ui.StatusLine("Status", ui.FormatStatus(status))
+		if s.Reason != "" {
+			ui.StatusLine("Reason", s.Reason)
+		}
+		ui.StatusLine("Iterations", fmt.Sprintf("%d/%d", s.Iterations, s.MaxIterations))
+		if s.TasksTotal > 0 {
+			ui.StatusLine("Progress", fmt.Sprintf("%d/%d items", s.TasksDone, s.TasksTotal))
+		}
+		if s.LastTests != "" {
+			ui.StatusLine("Tests", s.LastTests)
+		}
+		ui.StatusLine("Model", s.Model)
+		ui.StatusLine("Started", s.StartTime.Format("2006-01-02 15:04:05"))
+		end := time.Now()
+		if s.EndTime != nil {
+			end = *s.EndTime
+			ui.StatusLine("Ended", end.Format("2006-01-02 15:04:05"))
+		}
+		ui.StatusLine("Duration", ui.FormatDuration(end.Sub(s.StartTime)))
+		ui.StatusLine("PRD", s.PRDFile)
+		ui.StatusLine("Work dir", s.WorkDir)
+		ui.StatusLine("Log", s.LogFile)
+		for _, t := range s.Tasks {
+			line := fmt.Sprintf("%s (%s after %d iterations", t.Text, t.Status, t.Iterations)
+			if t.LastTests != "" {
+				line += ", tests " + t.LastTests
+			}
+			ui.StatusLine("Task", line+")")
+		}
+		for _, n := range s.Notes {
+			delivered := "queued"
+			if n.DeliveredAt != nil {
+				delivered = fmt.Sprintf("delivered in iteration %d", n.Iteration)
+			}
+			ui.StatusLine("Note", fmt.Sprintf("%s (%s)", n.Message, delivered))
+		}
+		return nil
+	},
+}
+
 var sessionStopCmd = &cobra.Command{
 	Use:   "stop [name]",
 	Short: "Stop a running session",
@@ -70,13 +180,227 @@ var sessionStopCmd = &cobra.Command{
 
 var sessionCleanCmd = &cobra.Command{
 	Use:   "clean",
-	Short: "Remove completed/stopped session files",
+	Short: "Remove completed/stopped/crashed session files",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		count, err := session.Clean()
+		opts, err := cleanOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if !opts.DryRun {
+			applyRetention()
+		}
+		cleaned, err := session.Clean(opts)
+		if err != nil {
+			return err
+		}
+		verb := "Cleaned"
+		switch {
+		case opts.DryRun:
+			verb = "Would clean"
+		case opts.Archive:
+			verb = "Archived"
+		}
+		for _, s := range cleaned {
+			ui.Dim(fmt.Sprintf("  %s (%s, started %s)", s.Name, s.Status, s.StartTime.Format("2006-01-02 15:04")))
+		}
+		ui.Success(fmt.Sprintf("%s %d session(s).", verb, len(cleaned)))
+		return nil
+	},
+}
+
+func cleanOptionsFromFlags(cmd *cobra.Command) (session.CleanOptions, error) {
+	var opts session.CleanOptions
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	if olderThan != "" {
+		d, err := parseAge(olderThan)
+		if err != nil {
+			return opts, err
+		}
+		opts.OlderThan = d
+	}
+	opts.Statuses, _ = cmd.Flags().GetStringSlice("status")
+	for _, st := range opts.Statuses {
+		if st == session.StatusRunning || st == session.StatusPaused {
+			return opts, fmt.Errorf("%s sessions cannot be cleaned; stop them first", st)
+		}
+	}
+	if err := checkStatuses(opts.Statuses, []string{session.StatusComplete, session.StatusStopped, session.StatusCrashed}); err != nil {
+		return opts, err
+	}
+	opts.KeepLast, _ = cmd.Flags().GetInt("keep-last")
+	opts.Archive, _ = cmd.Flags().GetBool("archive")
+	opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	return opts, nil
+}
+
+// checkStatuses returns an error naming the valid statuses if any of
+// statuses isn't one of them.
+func checkStatuses(statuses, valid []string) error {
+	for _, st := range statuses {
+		if !slices.Contains(valid, st) {
+			return fmt.Errorf("unknown status %q (valid: %s)", st, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+// parseAge parses a duration that may also use d (days) and w (weeks)
+// units, e.g. "7d" or "2w".
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 12h, 7d, 2w)", s)
+	}
+	return d, nil
+}
+
+// usesSessions annotates commands that read or write sessions, including
+// their subcommands. Only those get the session store configured, since
+// doing so looks up the repository and may create its sessions directory.
+var usesSessions = map[string]string{"sessions": "true"}
+
+// needsSessions reports whether cmd or one of its parents uses sessions.
+func needsSessions(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations["sessions"] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// projectSessionsDir is the current repository's sessions directory, if the
+// working directory is inside a git repository.
+var projectSessionsDir string
+
+// configureSessionStore points the session package at the global store
+// (RALPHKIT_HOME or the sessions_dir config key) and at the sessions
+// directory of the repository containing dir. With project_sessions set, new
+// sessions are stored in the project; otherwise in the global store. Both
+// are searched when listing and looking up sessions.
+func configureSessionStore(dir string) {
+	global := expandHome(viper.GetString("sessions_dir"))
+	if global == "" {
+		if home, err := paths.Home(); err == nil {
+			global = filepath.Join(home, "sessions")
+		}
+	}
+	projectSessionsDir = ""
+	if pd := paths.ProjectDir(dir); pd != "" {
+		projectSessionsDir = filepath.Join(pd, "sessions")
+	}
+	if viper.GetBool("project_sessions") && projectSessionsDir != "" {
+		if _, err := os.Stat(projectSessionsDir); os.IsNotExist(err) {
+			ignoreProjectSessions(filepath.Dir(projectSessionsDir))
+		}
+		session.StoreDir = projectSessionsDir
+		session.ExtraDirs = []string{global}
+	} else {
+		session.StoreDir = global
+		session.ExtraDirs = []string{projectSessionsDir}
+	}
+}
+
+// ignoreProjectSessions writes a .gitignore into a project's .ralphkit
+// directory, when it has none, so the sessions and archive stored there
+// stay out of git while config.yaml can still be committed.
+func ignoreProjectSessions(pd string) {
+	path := filepath.Join(pd, ".gitignore")
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	err := os.MkdirAll(pd, 0o755)
+	if err == nil {
+		err = os.WriteFile(path, []byte("sessions/\narchive/\n"), 0o644)
+	}
+	if err != nil {
+		ui.Warn(fmt.Sprintf("Could not write %s; add sessions/ and archive/ to it yourself: %v", path, err))
+		return
+	}
+	ui.Dim(fmt.Sprintf("Created %s to keep session files out of git.", path))
+}
+
+// expandHome expands a leading ~ in a configured path.
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// applyRetention enforces the retention_* config keys, if any are set, so the
+// sessions directory doesn't grow unbounded. It runs when a loop starts and
+// on 'session clean', never as a side effect of other commands.
+func applyRetention() {
+	olderThan := viper.GetString("retention_older_than")
+	keepLast := viper.GetInt("retention_keep_last")
+	if olderThan == "" && keepLast == 0 {
+		return
+	}
+	opts := session.CleanOptions{
+		KeepLast: keepLast,
+		Archive:  viper.GetBool("retention_archive"),
+	}
+	if olderThan != "" {
+		d, err := parseAge(olderThan)
 		if err != nil {
+			ui.Warn(fmt.Sprintf("Ignoring retention policy: %v", err))
+			return
+		}
+		opts.OlderThan = d
+	}
+	if _, err := session.Clean(opts); err != nil {
+		ui.Warn(fmt.Sprintf("Failed to apply retention policy: %v", err))
+	}
+}
+
+var sessionSayCmd = &cobra.Command{
+	Use:   "say [name] [message]",
+	Short: "Send guidance to a running session's next iteration",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		message := strings.Join(args[1:], " ")
+		if err := session.Say(args[0], message); err != nil {
+			return err
+		}
+		ui.Success(fmt.Sprintf("Queued note for session %q. It will be delivered on the next iteration.", args[0]))
+		return nil
+	},
+}
+
+var sessionPauseCmd = &cobra.Command{
+	Use:   "pause [name]",
+	Short: "Pause a running session before its next iteration",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Pause(args[0]); err != nil {
+			return err
+		}
+		ui.Success(fmt.Sprintf("Session %q will pause before its next iteration.", args[0]))
+		return nil
+	},
+}
+
+var sessionResumeCmd = &cobra.Command{
+	Use:   "resume [name]",
+	Short: "Resume a paused session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Resume(args[0]); err != nil {
 			return err
 		}
-		ui.Success(fmt.Sprintf("Cleaned %d session(s).", count))
+		ui.Success(fmt.Sprintf("Session %q resumed.", args[0]))
 		return nil
 	},
 }
@@ -1,30 +1,108 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/kfroemming/ralphkit/internal/session"
 	"github.com/kfroemming/ralphkit/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 func init() {
+	tailCmd.Flags().Int("from-iteration", 0, "Start output at iteration N")
+	tailCmd.Flags().IntP("lines", "n", 0, "Show only the last N lines of existing output before following")
+	tailCmd.Flags().String("only", "", "Show only one part of each iteration: agent or tests")
+	tailCmd.Flags().String("grep", "", "Show only lines matching this regular expression")
+	tailCmd.Flags().Bool("all-running", false, "Tail every running session, including ones that start later")
 	rootCmd.AddCommand(tailCmd)
 }
 
 var tailCmd = &cobra.Command{
-	Use:   "tail [session-name]",
-	Short: "Tail the live output of a running session",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runTail,
+	Use:         "tail [session-name|glob]...",
+	Annotations: usesSessions,
+	Short:       "Tail the live output of one or more sessions",
+	Long: `Tail the live output of one or more sessions.
+
+A single named session is streamed as-is; if it hasn't started yet, tail waits
+for it to appear. With several names, a glob such as 'api-*' or --all-running,
+logs are interleaved line by line with a colored [session-name] prefix, and
+matching sessions that start while tailing are picked up.`,
+	RunE: runTail,
 }
 
+// livenessInterval is how often tail rechecks sessions that have gone
+// quiet, to notice crashes that never update the state file and new
+// sessions that match a glob.
+const livenessInterval = 2 * time.Second
+
 func runTail(cmd *cobra.Command, args []string) error {
-	name := args[0]
-	s, err := session.Load(name)
+	allRunning, _ := cmd.Flags().GetBool("all-running")
+	if len(args) == 0 && !allRunning {
+		return fmt.Errorf("specify at least one session name or glob, or --all-running")
+	}
+	for _, arg := range args {
+		if _, err := filepath.Match(arg, ""); err != nil {
+			return fmt.Errorf("invalid session pattern %q: %w", arg, err)
+		}
+		if !isGlob(arg) {
+			if err := session.ValidateName(arg); err != nil {
+				return err
+			}
+		}
+	}
+	filter, err := tailFilterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	lastN, _ := cmd.Flags().GetInt("lines")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch sessions: %w", err)
+	}
+	defer watcher.Close()
+	dirs, err := session.Dirs()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	if len(args) == 1 && !isGlob(args[0]) && !allRunning {
+		return tailOne(watcher, args[0], filter, lastN)
+	}
+	m := &multiTail{
+		watcher:    watcher,
+		filter:     filter,
+		lastN:      lastN,
+		allRunning: allRunning,
+		tails:      map[string]*logTail{},
+	}
+	for _, arg := range args {
+		if isGlob(arg) {
+			m.globs = append(m.globs, arg)
+		} else {
+			m.names = append(m.names, arg)
+		}
+	}
+	return m.run()
+}
+
+// tailOne streams a single session's log without prefixes.
+func tailOne(watcher *fsnotify.Watcher, name string, filter tailFilter, lastN int) error {
+	s, err := waitForSession(watcher, name)
 	if err != nil {
 		return err
 	}
@@ -36,40 +114,407 @@ func runTail(cmd *cobra.Command, args []string) error {
 	ui.StatusLine("Log file", s.LogFile)
 	fmt.Println()
 
-	f, err := os.Open(s.LogFile)
-	if err != nil {
-		return fmt.Errorf("failed to open log: %w", err)
+	t := &logTail{filter: filter, emit: func(line string) { fmt.Println(line) }}
+	if err := waitForLog(watcher, t, s); err != nil {
+		return err
 	}
-	defer f.Close()
+	defer t.close()
 
-	// Stream existing content.
-	if _, err := io.Copy(os.Stdout, f); err != nil {
+	if err := t.readExisting(lastN); err != nil {
 		return err
 	}
 
-	// If session is still running, keep tailing.
-	if s.Status != "running" {
+	if sessionEnded(name) {
+		t.flush()
 		ui.Dim("Session is not running. Showing full log.")
 		return nil
 	}
 
 	ui.Dim("Streaming live output... (Ctrl+C to stop)")
-	buf := make([]byte, 4096)
+	ticker := time.NewTicker(livenessInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			switch filepath.Base(ev.Name) {
+			case filepath.Base(s.LogFile):
+				if err := t.readNew(); err != nil {
+					return err
+				}
+				continue
+			case name + ".json":
+			default:
+				continue
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			if err := t.readNew(); err != nil {
+				return err
+			}
+		}
+		if sessionEnded(name) {
+			// Drain remaining.
+			t.readNew()
+			t.flush()
+			ui.Dim("\nSession ended.")
+			return nil
+		}
+	}
+}
+
+// multiTail interleaves the logs of several sessions, prefixing each line
+// with the session name.
+type multiTail struct {
+	watcher    *fsnotify.Watcher
+	filter     tailFilter
+	lastN      int
+	names      []string
+	globs      []string
+	allRunning bool
+	tails      map[string]*logTail
+	finished   map[string]bool
+	colors     int
+}
+
+// following reports whether new sessions may still join, in which case tail
+// runs until interrupted.
+func (m *multiTail) following() bool {
+	return m.allRunning || len(m.globs) > 0
+}
+
+func (m *multiTail) run() error {
+	m.finished = map[string]bool{}
+	m.discover(true)
+	if len(m.names) > 0 && !m.following() && m.allFinished() {
+		return nil
+	}
+	ui.Dim("Streaming live output... (Ctrl+C to stop)")
+
+	ticker := time.NewTicker(livenessInterval)
+	defer ticker.Stop()
 	for {
-		n, err := f.Read(buf)
+		select {
+		case ev, ok := <-m.watcher.Events:
+			if !ok {
+				return nil
+			}
+			base := filepath.Base(ev.Name)
+			if name, ok := strings.CutSuffix(base, ".log"); ok {
+				if t := m.tails[name]; t != nil {
+					if err := t.readNew(); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			name, ok := strings.CutSuffix(base, ".json")
+			if !ok {
+				continue
+			}
+			if m.tails[name] != nil {
+				m.checkEnded(name)
+			} else if !m.finished[name] {
+				m.discover(false)
+			}
+		case err, ok := <-m.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			for name, t := range m.tails {
+				if err := t.readNew(); err != nil {
+					return err
+				}
+				m.checkEnded(name)
+			}
+			m.discover(false)
+		}
+		if !m.following() && m.allFinished() {
+			return nil
+		}
+	}
+}
+
+// discover starts tailing sessions that match but aren't tailed yet. Named
+// sessions are tailed whatever their status; glob and --all-running matches
+// only while running. On the first pass, existing output is shown too.
+func (m *multiTail) discover(initial bool) {
+	sessions, err := session.List()
+	if err != nil {
+		return
+	}
+	for _, s := range sessions {
+		if m.tails[s.Name] != nil || m.finished[s.Name] {
+			continue
+		}
+		named := slices.Contains(m.names, s.Name)
+		running := s.Status == "running" && session.Alive(s)
+		if !named && !(running && m.matchesGlob(s.Name)) {
+			continue
+		}
+		if s.LogFile == "" {
+			continue
+		}
+		prefix := ui.SessionPrefix(s.Name, m.colors)
+		t := &logTail{filter: m.filter, emit: func(line string) { fmt.Println(prefix + " " + line) }}
+		if err := t.open(s.LogFile); err != nil {
+			// The loop creates its log right after the state file; retry later.
+			continue
+		}
+		m.colors++
+		m.tails[s.Name] = t
+		if !initial {
+			t.emit(ui.Separator("session started"))
+		}
+		t.readExisting(m.lastN)
+		if !running {
+			t.flush()
+			t.close()
+			delete(m.tails, s.Name)
+			m.finished[s.Name] = true
+		}
+	}
+}
+
+func (m *multiTail) matchesGlob(name string) bool {
+	if m.allRunning {
+		return true
+	}
+	for _, g := range m.globs {
+		if ok, _ := filepath.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEnded drains and stops tailing a session that is no longer running.
+func (m *multiTail) checkEnded(name string) {
+	t := m.tails[name]
+	if t == nil || !sessionEnded(name) {
+		return
+	}
+	t.readNew()
+	t.flush()
+	t.emit(ui.Separator("session ended"))
+	t.close()
+	delete(m.tails, name)
+	m.finished[name] = true
+}
+
+// allFinished reports whether every explicitly named session has ended.
+func (m *multiTail) allFinished() bool {
+	for _, name := range m.names {
+		if !m.finished[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+func tailFilterFromFlags(cmd *cobra.Command) (tailFilter, error) {
+	var f tailFilter
+	f.fromIteration, _ = cmd.Flags().GetInt("from-iteration")
+	f.only, _ = cmd.Flags().GetString("only")
+	if f.only != "" && f.only != session.SectionAgent && f.only != session.SectionTests {
+		return f, fmt.Errorf("--only must be %q or %q", session.SectionAgent, session.SectionTests)
+	}
+	pattern, _ := cmd.Flags().GetString("grep")
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return f, fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+		f.grep = re
+	}
+	return f, nil
+}
+
+// waitForSession loads a session, waiting for its state file to appear if
+// it hasn't started yet.
+func waitForSession(watcher *fsnotify.Watcher, name string) (*session.State, error) {
+	s, err := session.Load(name)
+	if err == nil {
+		return s, nil
+	}
+	ui.Dim(fmt.Sprintf("Waiting for session %q to start... (Ctrl+C to stop)", name))
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil, fmt.Errorf("stopped watching for session %q", name)
+			}
+			if filepath.Base(ev.Name) != name+".json" {
+				continue
+			}
+			if s, err := session.Load(name); err == nil {
+				return s, nil
+			}
+		case err := <-watcher.Errors:
+			return nil, err
+		}
+	}
+}
+
+// waitForLog opens a session's log into t, waiting for the loop to create
+// it if needed.
+func waitForLog(watcher *fsnotify.Watcher, t *logTail, s *session.State) error {
+	for {
+		err := t.open(s.LogFile)
+		if err == nil {
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to open log: %w", err)
+		}
+		if sessionEnded(s.Name) {
+			return fmt.Errorf("session %q ended without writing a log", s.Name)
+		}
+		select {
+		case <-watcher.Events:
+		case err := <-watcher.Errors:
+			return err
+		case <-time.After(livenessInterval):
+		}
+	}
+}
+
+// sessionEnded reports whether a session is no longer running.
+func sessionEnded(name string) bool {
+	s, err := session.Load(name)
+	return err != nil || s.Status != "running" || !session.Alive(s)
+}
+
+// tailFilter selects which parts of a session log are shown.
+type tailFilter struct {
+	fromIteration int
+	only          string
+	grep          *regexp.Regexp
+}
+
+// logTail incrementally reads a session log, tracking which iteration and
+// section each line belongs to and passing visible lines to emit.
+type logTail struct {
+	filter    tailFilter
+	emit      func(line string)
+	file      *os.File
+	partial   []byte
+	iteration int
+	section   string
+}
+
+func (t *logTail) open(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	t.file = f
+	t.section = session.SectionAgent
+	return nil
+}
+
+func (t *logTail) close() {
+	if t.file != nil {
+		t.file.Close()
+	}
+}
+
+// readExisting consumes the log written so far, emitting only the last n
+// visible lines if n > 0.
+func (t *logTail) readExisting(n int) error {
+	if n <= 0 {
+		return t.readNew()
+	}
+	var ring []string
+	emit := t.emit
+	t.emit = func(line string) {
+		ring = append(ring, line)
+		if len(ring) > n {
+			ring = ring[1:]
+		}
+	}
+	err := t.readNew()
+	t.emit = emit
+	for _, line := range ring {
+		emit(line)
+	}
+	return err
+}
+
+// readNew consumes everything appended to the log since the last read.
+func (t *logTail) readNew() error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := t.file.Read(buf)
 		if n > 0 {
-			os.Stdout.Write(buf[:n])
+			t.feed(buf[:n])
+		}
+		if err == io.EOF {
+			return nil
 		}
 		if err != nil {
-			time.Sleep(200 * time.Millisecond)
-			// Recheck if session is still running.
-			s, _ = session.Load(name)
-			if s == nil || s.Status != "running" {
-				// Drain remaining.
-				io.Copy(os.Stdout, f)
-				ui.Dim("\nSession ended.")
-				return nil
+			return err
+		}
+	}
+}
+
+func (t *logTail) feed(data []byte) {
+	t.partial = append(t.partial, data...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			return
+		}
+		line := string(t.partial[:i])
+		t.partial = t.partial[i+1:]
+		t.line(line)
+	}
+}
+
+// flush emits a trailing line that has no newline yet.
+func (t *logTail) flush() {
+	if len(t.partial) > 0 {
+		t.line(string(t.partial))
+		t.partial = nil
+	}
+}
+
+func (t *logTail) line(line string) {
+	if section, iteration, ok := session.ParseMarker(line); ok {
+		t.section, t.iteration = section, iteration
+		if t.visible() {
+			label := fmt.Sprintf("iteration %d", iteration)
+			if section == session.SectionTests {
+				label = fmt.Sprintf("tests · iteration %d", iteration)
 			}
+			t.emit(ui.Separator(label))
 		}
+		return
+	}
+	if !t.visible() {
+		return
+	}
+	if t.filter.grep != nil && !t.filter.grep.MatchString(line) {
+		return
+	}
+	t.emit(line)
+}
+
+func (t *logTail) visible() bool {
+	if t.iteration < t.filter.fromIteration {
+		return false
 	}
+	return t.filter.only == "" || t.section == t.filter.only
 }
@@ -0,0 +1,142 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/ui"
+)
+
+// Hook points, in the order they run.
+const (
+	HookPreRun        = "pre_run"
+	HookPreIteration  = "pre_iteration"
+	HookPreTests      = "pre_tests"
+	HookPostTests     = "post_tests"
+	HookPostIteration = "post_iteration"
+	HookOnComplete    = "on_complete"
+	HookOnFailure     = "on_failure"
+)
+
+// HookNames lists every hook point.
+var HookNames = []string{HookPreRun, HookPreIteration, HookPreTests, HookPostTests, HookPostIteration, HookOnComplete, HookOnFailure}
+
+// What to do when a hook fails.
+const (
+	HookAbort  = "abort"  // stop the loop with an error
+	HookReport = "report" // show the failure to the agent in the next prompt
+	HookIgnore = "ignore" // warn and carry on
+)
+
+// DefaultHookTimeout bounds a hook with no timeout configured.
+const DefaultHookTimeout = 10 * time.Minute
+
+// maxHookReport caps how much hook output is passed to the agent.
+const maxHookReport = 4000
+
+// Hook is a shell command run at a point in the loop.
+type Hook struct {
+	Command string
+	Timeout time.Duration
+	// Policy is HookAbort, HookReport or HookIgnore.
+	Policy string
+}
+
+// hookRunner runs the configured hooks for one session and collects
+// reported failures for the next prompt.
+type hookRunner struct {
+	hooks  map[string]Hook
+	state  *session.State
+	cfg    Config
+	log    io.Writer
+	report strings.Builder
+}
+
+// errHookAborted marks a hook failure whose policy is to abort the loop.
+var errHookAborted = errors.New("hook failed")
+
+// run executes the named hook, if configured. It returns an error only if
+// the hook failed and its policy is to abort.
+func (h *hookRunner) run(ctx context.Context, name string) error {
+	hook, ok := h.hooks[name]
+	if !ok || hook.Command == "" {
+		return nil
+	}
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ui.Dim(fmt.Sprintf("Running %s hook: %s", name, hook.Command))
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(hookCtx, "cmd", "/C", hook.Command)
+	} else {
+		cmd = exec.CommandContext(hookCtx, "sh", "-c", hook.Command)
+	}
+	cmd.Dir = h.cfg.WorkDir
+	cmd.Env = append(os.Environ(), h.env(name)...)
+	var buf bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&buf, h.log)
+	cmd.Stderr = io.MultiWriter(&buf, h.log)
+	err := cmd.Run()
+	if err == nil || ctx.Err() != nil {
+		return nil
+	}
+	if hookCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	switch hook.Policy {
+	case HookAbort:
+		ui.Error(fmt.Sprintf("%s hook failed: %v", name, err))
+		return fmt.Errorf("%w: %s: %v", errHookAborted, name, err)
+	case HookIgnore:
+		ui.Warn(fmt.Sprintf("%s hook failed: %v", name, err))
+	default:
+		ui.Warn(fmt.Sprintf("%s hook failed: %v (reported to the agent)", name, err))
+		output := strings.TrimSpace(buf.String())
+		if len(output) > maxHookReport {
+			output = "..." + output[len(output)-maxHookReport:]
+		}
+		fmt.Fprintf(&h.report, "The %s hook (%s) failed: %v\n%s\n\n", name, hook.Command, err, output)
+	}
+	return nil
+}
+
+// takeReport returns and clears the failures reported since the last call.
+func (h *hookRunner) takeReport() string {
+	r := strings.TrimSpace(h.report.String())
+	h.report.Reset()
+	return r
+}
+
+func (h *hookRunner) env(name string) []string {
+	s := h.state
+	return []string{
+		"RALPHKIT_HOOK=" + name,
+		"RALPHKIT_SESSION=" + s.Name,
+		"RALPHKIT_STATUS=" + s.Status,
+		"RALPHKIT_ITERATION=" + strconv.Itoa(s.Iterations),
+		"RALPHKIT_MAX_ITERATIONS=" + strconv.Itoa(s.MaxIterations),
+		"RALPHKIT_MODEL=" + s.Model,
+		"RALPHKIT_WORKDIR=" + s.WorkDir,
+		"RALPHKIT_PRD=" + s.PRDFile,
+		"RALPHKIT_LOG=" + s.LogFile,
+		"RALPHKIT_TESTS=" + s.LastTests,
+		"RALPHKIT_TASKS_DONE=" + strconv.Itoa(s.TasksDone),
+		"RALPHKIT_TASKS_TOTAL=" + strconv.Itoa(s.TasksTotal),
+	}
+}
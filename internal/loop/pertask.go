@@ -0,0 +1,126 @@
+package loop
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kfroemming/ralphkit/internal/prd"
+	"github.com/kfroemming/ralphkit/internal/session"
+)
+
+// taskDoneMarker is what the agent prints in per-task mode once the current
+// task is finished.
+const taskDoneMarker = "TASK_DONE"
+
+// nextTask returns the first unchecked task of the PRD that hasn't failed in
+// this session, or nil if there is none.
+func nextTask(spec string, state *session.State) *prd.Task {
+	for _, t := range prd.Parse(spec).Remaining() {
+		if run := state.Task(t.ID); run == nil || run.Status != session.TaskFailed {
+			return t
+		}
+	}
+	return nil
+}
+
+// startTask returns the session's record of task t, adding it if this is
+// the first iteration spent on it.
+func startTask(state *session.State, t *prd.Task) *session.TaskRun {
+	if run := state.Task(t.ID); run != nil {
+		run.Status = session.TaskWorking
+		return run
+	}
+	run := &session.TaskRun{ID: t.ID, Text: t.Text, Status: session.TaskWorking, StartTime: time.Now()}
+	state.Tasks = append(state.Tasks, run)
+	return run
+}
+
+// endTask records the outcome of a task run.
+func endTask(state *session.State, run *session.TaskRun, status string) {
+	now := time.Now()
+	run.Status = status
+	run.EndTime = &now
+	recordEvent(state, session.Event{Type: session.EventTask, Iteration: state.Iterations, Task: run.ID,
+		Status: status, Duration: now.Sub(run.StartTime).Seconds(), Message: run.Text})
+}
+
+// taskClaimedDone reports whether the agent says it finished the current
+// task, either by printing the marker or by ticking the task itself. ALL_DONE
+// doesn't count: it speaks for the whole PRD, not this task.
+func taskClaimedDone(cfg Config, id, output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == taskDoneMarker {
+			return true
+		}
+	}
+	t := prd.Parse(currentPRD(cfg)).Task(id)
+	return t != nil && t.Done
+}
+
+// setTaskDone ticks or unticks a task in the PRD file.
+func setTaskDone(cfg Config, id string, done bool) error {
+	if cfg.PRDFile == "" {
+		return fmt.Errorf("no PRD file to update")
+	}
+	doc, err := prd.ParseFile(cfg.PRDFile)
+	if err != nil {
+		return err
+	}
+	t := doc.Task(id)
+	if t == nil {
+		return fmt.Errorf("task %q is no longer in %s", id, cfg.PRDFile)
+	}
+	if t.Done == done {
+		return nil
+	}
+	doc.SetDone(t, done)
+	return doc.WriteFile(cfg.PRDFile)
+}
+
+// BuildTaskPrompt is the exported version of buildTaskPrompt for use in
+// dry-run mode.
+func BuildTaskPrompt(spec string, t *prd.Task, testResults, hookReport string, notes []session.Note, iteration int) string {
+	return buildTaskPrompt(spec, t, testResults, hookReport, notes, iteration)
+}
+
+// buildTaskPrompt asks the agent to work on a single task, with the whole
+// PRD as background.
+func buildTaskPrompt(spec string, t *prd.Task, testResults, hookReport string, notes []session.Note, iteration int) string {
+	var b strings.Builder
+	if len(notes) > 0 {
+		b.WriteString("IMPORTANT — guidance from the human operator watching this session. Follow it before anything else:\n")
+		for _, n := range notes {
+			b.WriteString("- ")
+			b.WriteString(n.Message)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("You are working on a coding task, one item of a specification at a time. The full specification, for background:\n\n")
+	b.WriteString(spec)
+
+	b.WriteString("\n\nYour task now is this item, and only this item:\n\n")
+	b.WriteString(t.Text)
+	if t.Feature != nil && t.Feature.Name != t.Text {
+		b.WriteString("\n\nIt belongs to the feature: " + t.Feature.Name)
+	}
+	if t.Section != nil {
+		b.WriteString("\nIt is listed under: " + t.Section.Title)
+	}
+	b.WriteString("\n\nDon't start on other items, don't edit the checkboxes in the specification and keep its <!-- id: ... --> comments; ralphkit ticks the item off once tests pass. " +
+		"When this item is finished, output the exact string " + taskDoneMarker + " on its own line.")
+
+	if testResults != "" {
+		b.WriteString("\n\nIf tests were run, here are the results:\n")
+		b.WriteString(testResults)
+	}
+
+	if hookReport != "" {
+		b.WriteString("\n\nProject hooks failed since your last iteration. Fix the cause if it is in the code:\n")
+		b.WriteString(hookReport)
+	}
+
+	b.WriteString(fmt.Sprintf("\n\nCurrent iteration: %d.", iteration))
+	return b.String()
+}
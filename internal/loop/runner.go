@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,143 +14,457 @@ import (
 	"time"
 
 	"github.com/kfroemming/ralphkit/internal/detect"
+	"github.com/kfroemming/ralphkit/internal/notify"
+	"github.com/kfroemming/ralphkit/internal/prd"
 	"github.com/kfroemming/ralphkit/internal/session"
 	"github.com/kfroemming/ralphkit/internal/ui"
 )
 
 // Config holds all parameters for a Ralph loop run.
 type Config struct {
-	PRDContent    string
-	Model         string
-	MaxIterations int
-	SkipTests     bool
-	WorkDir       string
-	SessionName   string
+	PRDContent string
+	// PRDFile is the path PRDContent was read from. Progress is measured
+	// against its current contents, since the agent ticks items off there.
+	PRDFile                    string
+	Model                      string
+	MaxIterations              int
+	SkipTests                  bool
+	WorkDir                    string
+	SessionName                string
 	DangerouslySkipPermissions bool
-	Quiet         bool
+	Quiet                      bool
+	// Force reuses the name of a finished session, archiving its previous run.
+	Force bool
+	// OnStart, if set, is called with the session name once the session is
+	// registered, which may differ from SessionName.
+	OnStart func(name string)
+	// Notifier, if set, is told when the loop completes, runs out of
+	// iterations or stalls.
+	Notifier notify.Notifier
+	// StallIterations is how many consecutive iterations without PRD
+	// progress count as a stall; 0 disables stall notifications.
+	StallIterations int
+	// StopOnStall ends the loop once it stalls instead of only notifying.
+	StopOnStall bool
+	// MaxDuration, if positive, is the time budget for the whole loop.
+	MaxDuration time.Duration
+	// Hooks maps hook points (HookPreRun, ...) to commands to run there.
+	Hooks map[string]Hook
+	// PerTask works through the PRD one unchecked task at a time, ticking
+	// each off once the agent finishes it and tests pass.
+	PerTask bool
+	// TaskMaxAttempts is how many iterations a task gets in per-task mode
+	// before it is marked failed and skipped; 0 means no limit.
+	TaskMaxAttempts int
 }
 
 // completionMarkers are strings that signal the agent considers the PRD complete.
 var completionMarkers = []string{"ALL_DONE", "PRD_COMPLETE"}
 
-// Run executes the Ralph loop.
-func Run(ctx context.Context, cfg Config) error {
+// Run executes the Ralph loop and returns the session's final state, which
+// records why the loop ended. The state is nil if the session could not be
+// created.
+func Run(ctx context.Context, cfg Config) (*session.State, error) {
 	startTime := time.Now()
-
-	logPath, err := session.LogPath(cfg.SessionName)
-	if err != nil {
-		return fmt.Errorf("failed to get log path: %w", err)
+	interrupted := ctx
+	if cfg.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.MaxDuration)
+		defer cancel()
 	}
-	logFile, err := os.Create(logPath)
-	if err != nil {
-		return fmt.Errorf("failed to create log file: %w", err)
+	// stopReason tells a time budget running out from an interruption.
+	stopReason := func() string {
+		if interrupted.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+			return session.ReasonBudget
+		}
+		return session.ReasonInterrupted
 	}
-	defer logFile.Close()
 
 	state := &session.State{
 		Name:          cfg.SessionName,
 		Status:        "running",
-		PID:           os.Getpid(),
 		Iterations:    0,
 		MaxIterations: cfg.MaxIterations,
 		Model:         cfg.Model,
 		WorkDir:       cfg.WorkDir,
-		PRDFile:       "",
+		PRDFile:       cfg.PRDFile,
 		StartTime:     startTime,
-		LogFile:       logPath,
 	}
-	if err := session.Save(state); err != nil {
-		return fmt.Errorf("failed to save session state: %w", err)
+	state.Identify()
+	if err := session.Create(state, cfg.Force); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if state.Name != cfg.SessionName {
+		ui.Warn(fmt.Sprintf("Session %q already exists; using %q.", cfg.SessionName, state.Name))
+	}
+	stopHeartbeat := startHeartbeat(state.Name, state.Nonce)
+	defer stopHeartbeat()
+	if cfg.OnStart != nil {
+		cfg.OnStart(state.Name)
+	}
+	recordEvent(state, session.Event{Type: session.EventStart, Snapshot: snapshotTree(cfg.WorkDir)})
+
+	logFile, err := os.Create(state.LogFile)
+	if err != nil {
+		finish(state, "stopped", session.ReasonAgentError)
+		return state, fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer logFile.Close()
+
+	hooks := &hookRunner{hooks: cfg.Hooks, state: state, cfg: cfg, log: logFile}
+	// fail ends the run early for reason, running the on_failure hook.
+	fail := func(reason string, err error) (*session.State, error) {
+		finish(state, "stopped", reason)
+		_ = hooks.run(context.Background(), HookOnFailure)
+		return state, err
+	}
+	// stopped ends the run after an interruption or an exhausted budget.
+	stopped := func() (*session.State, error) {
+		reason := stopReason()
+		if reason == session.ReasonBudget {
+			ui.Warn(fmt.Sprintf("Time budget of %s exhausted. Saving session state...", cfg.MaxDuration))
+			fail(reason, nil)
+			sendNotification(cfg, state, notify.Budget)
+			return state, nil
+		}
+		ui.Warn("\nInterrupted. Saving session state...")
+		finish(state, "stopped", reason)
+		return state, nil
+	}
+	// complete ends the run once the PRD is done.
+	complete := func() (*session.State, error) {
+		finish(state, "complete", session.ReasonComplete)
+		_ = hooks.run(ctx, HookOnComplete)
+		sendNotification(cfg, state, notify.Complete)
+		ui.Celebration(state.Iterations, time.Since(startTime))
+		return state, nil
+	}
+	// tasksLeft ends a per-task run once there is no task to work on: complete
+	// if every box is ticked, failed if the rest used up their attempts.
+	tasksLeft := func() (*session.State, error) {
+		if n := len(prd.Parse(currentPRD(cfg)).Remaining()); n > 0 {
+			ui.Warn(fmt.Sprintf("%d task(s) left, all out of attempts.", n))
+			fail(session.ReasonTasksFailed, nil)
+			sendNotification(cfg, state, notify.Failed)
+			return state, nil
+		}
+		return complete()
+	}
+	if err := hooks.run(ctx, HookPreRun); err != nil {
+		return fail(session.ReasonHookFailed, err)
 	}
 
 	var testResults string
+	// Stall tracking: iterations since the PRD checklist last moved.
+	lastDone, _ := prd.Parse(currentPRD(cfg)).Progress()
+	sinceProgress := 0
+	// stalled tracks progress after an iteration and reports whether the
+	// loop has stalled and should stop.
+	stalled := func() bool {
+		if state.TasksTotal == 0 {
+			return false
+		}
+		if state.TasksDone > lastDone {
+			lastDone, sinceProgress = state.TasksDone, 0
+			return false
+		}
+		sinceProgress++
+		if sinceProgress != cfg.StallIterations {
+			return false
+		}
+		ui.Warn(fmt.Sprintf("No PRD progress in %d iterations.", sinceProgress))
+		if cfg.StopOnStall {
+			fail(session.ReasonStalled, nil)
+		}
+		sendNotification(cfg, state, notify.Stalled)
+		return cfg.StopOnStall
+	}
 
 	for i := 1; i <= cfg.MaxIterations; i++ {
-		select {
-		case <-ctx.Done():
-			ui.Warn("\nInterrupted. Saving session state...")
-			now := time.Now()
-			state.Status = "stopped"
-			state.EndTime = &now
-			_ = session.Save(state)
-			return nil
-		default:
+		waitWhilePaused(ctx, state)
+		if ctx.Err() != nil {
+			return stopped()
+		}
+
+		var task *prd.Task
+		var taskRun *session.TaskRun
+		if cfg.PerTask {
+			if task = nextTask(currentPRD(cfg), state); task == nil {
+				return tasksLeft()
+			}
+			taskRun = startTask(state, task)
+			taskRun.Iterations++
 		}
 
 		state.Iterations = i
+		iterEvent := session.Event{Type: session.EventIteration, Iteration: i}
+		if task != nil {
+			iterEvent.Task = task.ID
+		}
+		recordEvent(state, iterEvent)
+		notes := takeNotes(state, i)
 		_ = session.Save(state)
 
 		elapsed := time.Since(startTime)
 		ui.IterationHeader(i, cfg.MaxIterations, elapsed)
+		if task != nil {
+			ui.StatusLine("Task", fmt.Sprintf("%s (attempt %d)", task.Text, taskRun.Iterations))
+		}
+		for _, n := range notes {
+			ui.StatusLine("Operator note", n.Message)
+		}
+		if err := hooks.run(ctx, HookPreIteration); err != nil {
+			return fail(session.ReasonHookFailed, err)
+		}
 
-		prompt := buildPrompt(cfg.PRDContent, testResults, i)
+		var prompt string
+		if task != nil {
+			prompt = buildTaskPrompt(currentPRD(cfg), task, testResults, hooks.takeReport(), notes, i)
+		} else {
+			prompt = buildPrompt(currentPRD(cfg), testResults, hooks.takeReport(), notes, i)
+		}
 
-		output, err := runClaude(ctx, cfg, prompt, logFile)
+		fmt.Fprintln(logFile, session.Marker(session.SectionAgent, i))
+		agentStart := time.Now()
+		output, usage, err := runClaude(ctx, cfg, prompt, logFile)
 		if err != nil {
 			if ctx.Err() != nil {
-				now := time.Now()
-				state.Status = "stopped"
-				state.EndTime = &now
-				_ = session.Save(state)
-				ui.Warn("Session stopped.")
-				return nil
+				return stopped()
 			}
 			ui.Error(fmt.Sprintf("Claude exited with error: %v", err))
+			class := errorClass(err)
+			recordEvent(state, session.Event{Type: session.EventAgent, Iteration: i, Status: "error",
+				Class: class, Duration: time.Since(agentStart).Seconds(), Message: err.Error(),
+				Snapshot: snapshotTree(cfg.WorkDir), Usage: usage})
+			if class == "start" {
+				// Retrying cannot help if claude cannot be launched at all.
+				return fail(session.ReasonAgentError, err)
+			}
 			// Continue to next iteration rather than failing entirely.
+		} else {
+			recordEvent(state, session.Event{Type: session.EventAgent, Iteration: i, Status: "ok",
+				Duration: time.Since(agentStart).Seconds(), Snapshot: snapshotTree(cfg.WorkDir), Usage: usage})
 		}
 
-		ui.PrintLastLines(output, 10)
-
-		reportPRDProgress(cfg.PRDContent)
+		if cfg.Quiet {
+			// Otherwise the answer was already shown as it arrived.
+			ui.PrintLastLines(output, 10)
+		}
 
-		if isComplete(output) {
-			now := time.Now()
-			state.Status = "complete"
-			state.EndTime = &now
+		if task == nil {
+			reportPRDProgress(state, currentPRD(cfg))
 			_ = session.Save(state)
-			ui.Celebration(i, time.Since(startTime))
-			return nil
+			if isComplete(output) {
+				return complete()
+			}
+			if stalled() {
+				return state, nil
+			}
 		}
 
 		// Run tests if enabled.
 		if !cfg.SkipTests {
-			testResults = runTests(ctx, cfg.WorkDir, logFile)
+			fmt.Fprintln(logFile, session.Marker(session.SectionTests, i))
+			if err := hooks.run(ctx, HookPreTests); err != nil {
+				return fail(session.ReasonHookFailed, err)
+			}
+			testsStart := time.Now()
+			testResults, state.LastTests = runTests(ctx, cfg.WorkDir, logFile)
+			_ = session.Save(state)
+			if state.LastTests != "" {
+				recordEvent(state, session.Event{Type: session.EventTests, Iteration: i, Status: state.LastTests,
+					Duration: time.Since(testsStart).Seconds()})
+			}
 			if testResults != "" {
 				ui.Dim("Test results captured for next iteration.")
 			}
+			if err := hooks.run(ctx, HookPostTests); err != nil {
+				return fail(session.ReasonHookFailed, err)
+			}
+		}
+
+		if task != nil {
+			// The task counts as done only once tests agree with the agent.
+			claimed := taskClaimedDone(cfg, task.ID, output)
+			taskRun.LastTests = state.LastTests
+			switch {
+			case claimed && state.LastTests != "failed":
+				if err := setTaskDone(cfg, task.ID, true); err != nil {
+					ui.Warn(fmt.Sprintf("Failed to tick off %q: %v", task.Text, err))
+				} else {
+					endTask(state, taskRun, session.TaskDone)
+					ui.Success(fmt.Sprintf("Task done: %s", task.Text))
+				}
+			case claimed:
+				if err := setTaskDone(cfg, task.ID, false); err != nil {
+					ui.Warn(fmt.Sprintf("Failed to untick %q: %v", task.Text, err))
+				}
+				ui.Warn("Tests failed; staying on this task.")
+			}
+			if taskRun.Status == session.TaskWorking && cfg.TaskMaxAttempts > 0 && taskRun.Iterations >= cfg.TaskMaxAttempts {
+				endTask(state, taskRun, session.TaskFailed)
+				ui.Warn(fmt.Sprintf("Giving up on %q after %d attempt(s); moving on.", task.Text, taskRun.Iterations))
+				// Moving on is a change of course, not a stall.
+				sinceProgress = 0
+			}
+			reportPRDProgress(state, currentPRD(cfg))
+			_ = session.Save(state)
+			if nextTask(currentPRD(cfg), state) == nil {
+				return tasksLeft()
+			}
+			if stalled() {
+				return state, nil
+			}
+		}
+
+		if err := hooks.run(ctx, HookPostIteration); err != nil {
+			return fail(session.ReasonHookFailed, err)
+		}
+
+		if session.StopRequested(state.Name) {
+			finish(state, "stopped", session.ReasonStopRequested)
+			ui.Warn(fmt.Sprintf("Stopped after iteration %d as requested.", i))
+			return state, nil
 		}
 	}
 
+	fail(session.ReasonMaxIterations, nil)
+	sendNotification(cfg, state, notify.MaxIterations)
+	ui.MaxIterationsWarning(cfg.MaxIterations)
+	return state, nil
+}
+
+// finish records the final status of a run and why it ended.
+func finish(state *session.State, status, reason string) {
+	for _, t := range state.Tasks {
+		if t.Status == session.TaskWorking {
+			endTask(state, t, session.TaskUnfinished)
+		}
+	}
 	now := time.Now()
-	state.Status = "stopped"
+	state.Status = status
+	state.Reason = reason
 	state.EndTime = &now
 	_ = session.Save(state)
-	ui.MaxIterationsWarning(cfg.MaxIterations)
-	return nil
+	recordEvent(state, session.Event{Type: session.EventEnd, Status: status, Message: reason,
+		Duration: now.Sub(state.StartTime).Seconds()})
+}
+
+// sendNotification tells cfg.Notifier about the session. Delivery failures
+// are reported but never stop the loop.
+func sendNotification(cfg Config, state *session.State, kind string) {
+	if cfg.Notifier == nil {
+		return
+	}
+	if err := cfg.Notifier.Notify(context.Background(), notify.ForSession(state, kind)); err != nil {
+		ui.Warn(fmt.Sprintf("Notification failed: %v", err))
+	}
+}
+
+// recordEvent appends to the session's event log. Failures are not fatal to
+// the loop.
+func recordEvent(state *session.State, e session.Event) {
+	if err := session.RecordEvent(state.Name, e); err != nil {
+		ui.Dim(fmt.Sprintf("Failed to record %s event: %v", e.Type, err))
+	}
 }
 
 // BuildPrompt is the exported version of buildPrompt for use in dry-run mode.
-func BuildPrompt(prd, testResults string, iteration int) string {
-	return buildPrompt(prd, testResults, iteration)
+func BuildPrompt(spec, testResults, hookReport string, notes []session.Note, iteration int) string {
+	return buildPrompt(spec, testResults, hookReport, notes, iteration)
 }
 
-func buildPrompt(prd, testResults string, iteration int) string {
+func buildPrompt(spec, testResults, hookReport string, notes []session.Note, iteration int) string {
 	var b strings.Builder
+	if len(notes) > 0 {
+		b.WriteString("IMPORTANT — guidance from the human operator watching this session. Follow it before anything else:\n")
+		for _, n := range notes {
+			b.WriteString("- ")
+			b.WriteString(n.Message)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
 	b.WriteString("You are working on a coding task. Here is the specification:\n\n")
-	b.WriteString(prd)
+	b.WriteString(spec)
 	b.WriteString("\n\nComplete all items in the specification. When you have completed EVERYTHING, output the exact string ALL_DONE on its own line.")
 
+	doc := prd.Parse(spec)
+	if remaining := doc.Remaining(); len(remaining) > 0 {
+		done, total := doc.Progress()
+		b.WriteString(fmt.Sprintf("\n\nChecklist: %d of %d tasks done. Tick each task off in the PRD file (change \"- [ ]\" to \"- [x]\") as you finish it. Remaining:", done, total))
+		for _, t := range remaining {
+			b.WriteString(fmt.Sprintf("\n- [%s] %s", t.ID, t.Text))
+			if t.Feature != nil && t.Feature.Name != t.Text {
+				b.WriteString(" (" + t.Feature.Name + ")")
+			}
+		}
+	}
+
 	if testResults != "" {
 		b.WriteString("\n\nIf tests were run, here are the results:\n")
 		b.WriteString(testResults)
 	}
 
+	if hookReport != "" {
+		b.WriteString("\n\nProject hooks failed since your last iteration. Fix the cause if it is in the code:\n")
+		b.WriteString(hookReport)
+	}
+
 	b.WriteString(fmt.Sprintf("\n\nCurrent iteration: %d. Items remaining: continue until all done.", iteration))
 	return b.String()
 }
 
-func runClaude(ctx context.Context, cfg Config, prompt string, logWriter io.Writer) (string, error) {
-	args := []string{"-p", prompt, "--model", cfg.Model}
+// startHeartbeat periodically refreshes the session heartbeat so other
+// ralphkit processes can tell the loop is still alive. The returned func
+// stops it.
+func startHeartbeat(name, nonce string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(session.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := session.Beat(name, nonce); err != nil {
+					ui.Warn(fmt.Sprintf("Heartbeat failed: %v", err))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// takeNotes drains the operator inbox and records the notes as delivered
+// in the given iteration.
+func takeNotes(state *session.State, iteration int) []session.Note {
+	notes, err := session.TakeNotes(state.Name)
+	if err != nil {
+		ui.Warn(fmt.Sprintf("Failed to read operator notes: %v", err))
+		return nil
+	}
+	now := time.Now()
+	for i := range notes {
+		notes[i].DeliveredAt = &now
+		notes[i].Iteration = iteration
+	}
+	state.Notes = append(state.Notes, notes...)
+	for _, n := range notes {
+		recordEvent(state, session.Event{Type: session.EventNote, Iteration: iteration, Message: n.Message})
+	}
+	return notes
+}
+
+// runClaude runs one agent invocation and returns its answer and, when
+// claude reports it, the tokens and spend it took. The answer is written to
+// logWriter as it arrives, so tail, the dashboard and the run view follow
+// the agent live.
+func runClaude(ctx context.Context, cfg Config, prompt string, logWriter io.Writer) (string, *session.Usage, error) {
+	// stream-json prints each message as it happens and ends with a result
+	// carrying usage and cost; it needs --verbose with -p.
+	args := []string{"-p", prompt, "--model", cfg.Model, "--output-format", "stream-json", "--verbose"}
 	if cfg.DangerouslySkipPermissions {
 		args = append([]string{"--dangerously-skip-permissions"}, args...)
 	}
@@ -158,36 +474,117 @@ func runClaude(ctx context.Context, cfg Config, prompt string, logWriter io.Writ
 
 	var outputBuf bytes.Buffer
 	multiOut := io.MultiWriter(&outputBuf, logWriter)
-	if !cfg.Quiet {
+	// A silenced UI means something else owns the terminal, such as the
+	// run view, so the agent must not write to it directly.
+	if !cfg.Quiet && !ui.Silent {
 		multiOut = io.MultiWriter(&outputBuf, logWriter, os.Stdout)
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	cmd.Stderr = os.Stderr
+	if ui.Silent {
+		cmd.Stderr = logWriter
+	}
 
 	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start claude: %w", err)
+		return "", nil, fmt.Errorf("failed to start claude: %w", err)
 	}
 
+	var usage *session.Usage
 	scanner := bufio.NewScanner(stdout)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
 	for scanner.Scan() {
-		line := scanner.Text()
-		fmt.Fprintln(multiOut, line)
+		text, u, ok := parseStreamLine(scanner.Text())
+		if u != nil {
+			usage = u
+		}
+		if ok && text != "" {
+			fmt.Fprintln(multiOut, strings.TrimRight(text, "\n"))
+		}
 	}
 
 	err = cmd.Wait()
-	return outputBuf.String(), err
+	return outputBuf.String(), usage, err
 }
 
-func runTests(ctx context.Context, workDir string, logWriter io.Writer) string {
+// streamEvent is one line of claude -p --output-format stream-json.
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Usage        struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// parseStreamLine returns the text to show for one line of agent output and,
+// for the final result, the usage it reports. ok is false for lines with
+// nothing to show, such as tool calls. A line that isn't JSON, as from a
+// claude without stream-json, is shown as it is.
+func parseStreamLine(line string) (text string, usage *session.Usage, ok bool) {
+	var e streamEvent
+	if err := json.Unmarshal([]byte(line), &e); err != nil || e.Type == "" {
+		return line, nil, true
+	}
+	switch e.Type {
+	case "assistant":
+		var parts []string
+		for _, c := range e.Message.Content {
+			if c.Type == "text" && c.Text != "" {
+				parts = append(parts, c.Text)
+			}
+		}
+		return strings.Join(parts, "\n"), nil, len(parts) > 0
+	case "result":
+		return "", &session.Usage{
+			InputTokens:      e.Usage.InputTokens,
+			OutputTokens:     e.Usage.OutputTokens,
+			CacheReadTokens:  e.Usage.CacheReadInputTokens,
+			CacheWriteTokens: e.Usage.CacheCreationInputTokens,
+			CostUSD:          e.TotalCostUSD,
+		}, false
+	}
+	return "", nil, false
+}
+
+// errorClass sorts an agent failure into a coarse class for reporting:
+// "start" if claude could not be launched, "signal" if it was killed,
+// "exit" for a non-zero exit status, and "other" otherwise.
+func errorClass(err error) string {
+	var exitErr *exec.ExitError
+	var execErr *exec.Error
+	var pathErr *os.PathError
+	switch {
+	case errors.As(err, &exitErr):
+		if status, ok := exitErr.Sys().(interface{ Signaled() bool }); ok && status.Signaled() {
+			return "signal"
+		}
+		return "exit"
+	case errors.As(err, &execErr), errors.As(err, &pathErr):
+		return "start"
+	default:
+		return "other"
+	}
+}
+
+// runTests runs the detected test command and returns its output and
+// "passed" or "failed", or empty strings if no test command applies.
+func runTests(ctx context.Context, workDir string, logWriter io.Writer) (string, string) {
 	pt := detect.Detect(workDir)
 	bin, args := detect.TestCommand(pt)
 	if bin == "" {
-		return ""
+		return "", ""
 	}
 
 	ui.Dim(fmt.Sprintf("Running tests: %s %s", bin, strings.Join(args, " ")))
@@ -203,23 +600,50 @@ func runTests(ctx context.Context, workDir string, logWriter io.Writer) string {
 	result := buf.String()
 	if err != nil {
 		result += fmt.Sprintf("\n(tests exited with error: %v)", err)
+		return result, "failed"
 	}
-	return result
+	return result, "passed"
 }
 
-// reportPRDProgress scans the PRD for markdown checkboxes and prints progress.
-func reportPRDProgress(prd string) {
-	complete := 0
-	incomplete := 0
-	for _, line := range strings.Split(prd, "\n") {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "- [x]") || strings.HasPrefix(trimmed, "- [X]") {
-			complete++
-		} else if strings.HasPrefix(trimmed, "- [ ]") {
-			incomplete++
+// currentPRD returns the PRD as it is now on disk, falling back to the
+// content the loop started with.
+func currentPRD(cfg Config) string {
+	if cfg.PRDFile != "" {
+		if data, err := os.ReadFile(cfg.PRDFile); err == nil {
+			return string(data)
+		}
+	}
+	return cfg.PRDContent
+}
+
+// waitWhilePaused blocks before an iteration while the operator has the
+// session paused.
+func waitWhilePaused(ctx context.Context, state *session.State) {
+	if !session.PauseRequested(state.Name) {
+		return
+	}
+	state.Paused = true
+	_ = session.Save(state)
+	ui.Warn(fmt.Sprintf("Paused. Resume with: ralphkit session resume %s", state.Name))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for session.PauseRequested(state.Name) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 	}
-	total := complete + incomplete
+	state.Paused = false
+	_ = session.Save(state)
+	ui.Dim("Resumed.")
+}
+
+// reportPRDProgress counts the PRD's checklist tasks, prints progress and
+// records it in the session state.
+func reportPRDProgress(state *session.State, spec string) {
+	complete, total := prd.Parse(spec).Progress()
+	state.TasksDone, state.TasksTotal = complete, total
 	if total == 0 {
 		return
 	}
@@ -0,0 +1,58 @@
+package loop
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotTree records the working tree of workDir, including untracked
+// files that are not ignored, as a git tree object and returns its hash.
+// It uses a scratch index so the user's index and worktree are untouched,
+// but the file contents are stored as loose objects in the repository,
+// where git gc prunes them once they are old enough. It returns "" if
+// workDir is not in a git repository with at least one commit, or git
+// fails.
+func snapshotTree(workDir string) string {
+	git := func(env []string, args ...string) (string, error) {
+		c := exec.Command("git", args...)
+		c.Dir = workDir
+		c.Env = append(os.Environ(), env...)
+		out, err := c.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	// A repository without commits is likely brand new or not meant to be
+	// one; don't fill it with objects.
+	if _, err := git(nil, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return ""
+	}
+	index, err := git(nil, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(workDir, index)
+	}
+	tmp, err := os.CreateTemp("", "ralphkit-index-*")
+	if err != nil {
+		return ""
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	// Starting from the real index lets git reuse its cached file stats.
+	if data, err := os.ReadFile(index); err == nil {
+		os.WriteFile(tmp.Name(), data, 0o600)
+	} else {
+		os.Remove(tmp.Name())
+	}
+	env := []string{"GIT_INDEX_FILE=" + tmp.Name()}
+	if _, err := git(env, "add", "-A"); err != nil {
+		return ""
+	}
+	tree, err := git(env, "write-tree")
+	if err != nil {
+		return ""
+	}
+	return tree
+}
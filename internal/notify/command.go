@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// Command runs a shell command for each event. The event is passed as JSON
+// on stdin and as RALPHKIT_* environment variables.
+type Command struct {
+	Command string
+}
+
+func (c Command) Notify(ctx context.Context, e Event) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.Command)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"RALPHKIT_EVENT="+e.Kind,
+		"RALPHKIT_SESSION="+e.Session,
+		"RALPHKIT_STATUS="+e.Status,
+		"RALPHKIT_ITERATIONS="+strconv.Itoa(e.Iterations),
+		"RALPHKIT_MAX_ITERATIONS="+strconv.Itoa(e.MaxIterations),
+		"RALPHKIT_DURATION="+strconv.Itoa(int(e.Duration.Seconds())),
+		"RALPHKIT_TESTS="+e.Tests,
+		"RALPHKIT_WORKDIR="+e.WorkDir,
+		"RALPHKIT_MESSAGE="+e.Text(),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify command: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// Desktop shows a macOS notification through osascript.
+type Desktop struct{}
+
+func (Desktop) Notify(ctx context.Context, e Event) error {
+	script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(e.Body()), strconv.Quote(e.Title()))
+	if out, err := exec.CommandContext(ctx, "osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript: %w: %s", err, out)
+	}
+	return nil
+}
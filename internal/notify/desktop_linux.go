@@ -0,0 +1,23 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// Desktop shows a notification through notify-send, which talks to the
+// session's D-Bus notification daemon.
+type Desktop struct{}
+
+func (Desktop) Notify(ctx context.Context, e Event) error {
+	urgency := "normal"
+	if e.Kind == Failed || e.Kind == Stalled {
+		urgency = "critical"
+	}
+	cmd := exec.CommandContext(ctx, "notify-send", "--app-name=ralphkit", "--urgency="+urgency, e.Title(), e.Body())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// Desktop notifications are not supported on this platform; use a webhook
+// or command sink instead.
+type Desktop struct{}
+
+func (Desktop) Notify(ctx context.Context, e Event) error {
+	return errors.New("desktop notifications are not supported on this platform")
+}
@@ -0,0 +1,150 @@
+// Package notify delivers loop outcomes to desktop notifications, chat
+// webhooks and shell commands.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/ui"
+)
+
+// Event kinds.
+const (
+	Complete      = "complete"        // the agent reported the PRD done
+	Failed        = "failed"          // the loop could not run
+	MaxIterations = "max_iterations"  // the iteration limit was reached
+	Budget        = "budget_exceeded" // the time budget ran out
+	Stalled       = "stalled"         // several iterations made no PRD progress
+)
+
+// Kinds lists every event kind.
+var Kinds = []string{Complete, Failed, MaxIterations, Budget, Stalled}
+
+// sendTimeout bounds how long a single sink may take.
+const sendTimeout = 15 * time.Second
+
+// Event describes something worth telling the operator about.
+type Event struct {
+	Kind          string        `json:"event"`
+	Session       string        `json:"session"`
+	Status        string        `json:"status,omitempty"`
+	Iterations    int           `json:"iterations"`
+	MaxIterations int           `json:"maxIterations"`
+	Duration      time.Duration `json:"-"`
+	Tests         string        `json:"tests,omitempty"`
+	TasksDone     int           `json:"tasksDone"`
+	TasksTotal    int           `json:"tasksTotal"`
+	WorkDir       string        `json:"workDir,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// ForSession describes a session's current state as an event of kind.
+func ForSession(s *session.State, kind string) Event {
+	e := Event{
+		Kind:          kind,
+		Session:       s.Name,
+		Status:        s.Status,
+		Iterations:    s.Iterations,
+		MaxIterations: s.MaxIterations,
+		Duration:      time.Since(s.StartTime),
+		Tests:         s.LastTests,
+		TasksDone:     s.TasksDone,
+		TasksTotal:    s.TasksTotal,
+		WorkDir:       s.WorkDir,
+	}
+	if s.EndTime != nil {
+		e.Duration = s.EndTime.Sub(s.StartTime)
+	}
+	return e
+}
+
+// Title is a short headline for the event.
+func (e Event) Title() string {
+	switch e.Kind {
+	case Complete:
+		return fmt.Sprintf("ralphkit: %s complete", e.Session)
+	case Failed:
+		return fmt.Sprintf("ralphkit: %s failed", e.Session)
+	case MaxIterations:
+		return fmt.Sprintf("ralphkit: %s hit max iterations", e.Session)
+	case Budget:
+		return fmt.Sprintf("ralphkit: %s ran out of time", e.Session)
+	case Stalled:
+		return fmt.Sprintf("ralphkit: %s is stalled", e.Session)
+	default:
+		return fmt.Sprintf("ralphkit: %s %s", e.Session, e.Kind)
+	}
+}
+
+// Body summarizes iterations, duration, PRD progress and tests.
+func (e Event) Body() string {
+	parts := []string{fmt.Sprintf("%d/%d iterations in %s", e.Iterations, e.MaxIterations, ui.FormatDuration(e.Duration))}
+	if e.TasksTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d PRD items", e.TasksDone, e.TasksTotal))
+	}
+	if e.Tests != "" {
+		parts = append(parts, "tests "+e.Tests)
+	}
+	body := strings.Join(parts, ", ")
+	if e.Error != "" {
+		body += ": " + e.Error
+	}
+	return body
+}
+
+// Text is the title and body on one line, for chat messages and logs.
+func (e Event) Text() string {
+	return e.Title() + " — " + e.Body()
+}
+
+// Notifier delivers an event somewhere.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Multi sends each event to every notifier in order, subject to the kinds
+// filter. A nil or empty Multi does nothing.
+type Multi struct {
+	Notifiers []Notifier
+	// On restricts delivery to these kinds; empty means all kinds.
+	On []string
+}
+
+// Add appends a notifier.
+func (m *Multi) Add(n Notifier) {
+	m.Notifiers = append(m.Notifiers, n)
+}
+
+// Notify delivers e to every notifier, each under its own timeout, and
+// joins any errors.
+func (m *Multi) Notify(ctx context.Context, e Event) error {
+	if m == nil || !m.wants(e.Kind) {
+		return nil
+	}
+	var errs []error
+	for _, n := range m.Notifiers {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := n.Notify(sendCtx, e); err != nil {
+			errs = append(errs, err)
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Multi) wants(kind string) bool {
+	if len(m.On) == 0 {
+		return true
+	}
+	for _, k := range m.On {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
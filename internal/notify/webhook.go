@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Webhook payload formats.
+const (
+	FormatJSON    = "json"    // the event fields plus a "text" summary
+	FormatSlack   = "slack"   // {"text": ...} for Slack incoming webhooks
+	FormatDiscord = "discord" // {"content": ...} for Discord webhooks
+)
+
+// Webhook POSTs events as JSON.
+type Webhook struct {
+	URL    string
+	Format string
+}
+
+// WebhookFormat guesses the payload format from a webhook URL, falling back
+// to plain JSON.
+func WebhookFormat(url string) string {
+	switch {
+	case strings.Contains(url, "hooks.slack.com"):
+		return FormatSlack
+	case strings.Contains(url, "discord.com/api/webhooks"), strings.Contains(url, "discordapp.com/api/webhooks"):
+		return FormatDiscord
+	default:
+		return FormatJSON
+	}
+}
+
+func (w Webhook) Notify(ctx context.Context, e Event) error {
+	body, err := w.payload(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (w Webhook) payload(e Event) ([]byte, error) {
+	switch w.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", e.Title(), e.Body())})
+	case FormatDiscord:
+		return json.Marshal(map[string]string{"content": fmt.Sprintf("**%s**\n%s", e.Title(), e.Body())})
+	case FormatJSON, "":
+		return json.Marshal(struct {
+			Event
+			DurationSeconds float64 `json:"durationSeconds"`
+			Text            string  `json:"text"`
+		}{e, e.Duration.Seconds(), e.Text()})
+	default:
+		return nil, fmt.Errorf("webhook: unknown format %q (want json, slack or discord)", w.Format)
+	}
+}
@@ -0,0 +1,46 @@
+package paths
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HomeEnv names the environment variable that overrides the ralphkit home.
+const HomeEnv = "RALPHKIT_HOME"
+
+// Home returns the ralphkit home directory: $RALPHKIT_HOME if set,
+// otherwise ~/.ralphkit.
+func Home() (string, error) {
+	if h := os.Getenv(HomeEnv); h != "" {
+		return filepath.Abs(h)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ralphkit"), nil
+}
+
+// RepoRoot returns the top level of the git repository containing dir, or
+// "" if dir is not inside one.
+func RepoRoot(dir string) string {
+	c := exec.Command("git", "rev-parse", "--show-toplevel")
+	c.Dir = dir
+	out, err := c.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ProjectDir returns the per-project ralphkit directory for the repository
+// containing dir, or "" if dir is not inside one.
+func ProjectDir(dir string) string {
+	root := RepoRoot(dir)
+	if root == "" {
+		return ""
+	}
+	return filepath.Join(root, ".ralphkit")
+}
@@ -4,29 +4,115 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
-	"strings"
 )
 
 // Answers holds the user's responses from the PRD wizard.
 type Answers struct {
-	ProjectName  string
-	Description  string
-	TechStack    string
-	Features     string
-	OutOfScope   string
-	SuccessCrit  string
-	Constraints  string
-}
-
-// Generate calls Claude to expand rough notes into a structured PRD.
-func Generate(a Answers) (string, error) {
-	notes := formatNotes(a)
-	prompt := fmt.Sprintf(
-		"You are a product manager. Take these rough notes and turn them into a clear, structured PRD with sections: Overview, Goals, Non-Goals, Features (with acceptance criteria), Technical Approach, Success Metrics. Output ONLY the PRD in markdown.\n\nNotes:\n%s",
-		notes,
-	)
-
-	cmd := exec.Command("claude", "-p", prompt)
+	ProjectName string
+	Description string
+	TechStack   string
+	Features    string
+	OutOfScope  string
+	SuccessCrit string
+	Constraints string
+	// Extra holds answers to template-specific questions, by key.
+	Extra map[string]string
+	// Template is the kind of PRD to generate; nil means DefaultTemplate.
+	Template *Template
+	// RepoContext describes the repository the PRD will be carried out in,
+	// if any.
+	RepoContext string
+}
+
+// answerKeys are the keys of the Answers fields, as used by templates and
+// answers files.
+var answerKeys = []string{"description", "tech_stack", "features", "out_of_scope", "success_criteria", "constraints"}
+
+// answerLabels name the Answers fields in the notes sent to Claude when a
+// template doesn't ask for them itself.
+var answerLabels = map[string]string{
+	"description":      "Description",
+	"tech_stack":       "Tech Stack",
+	"features":         "Features",
+	"out_of_scope":     "Out of Scope",
+	"success_criteria": "Success Criteria",
+	"constraints":      "Constraints",
+}
+
+// field returns the Answers field for key, or nil for other keys.
+func (a *Answers) field(key string) *string {
+	switch key {
+	case "project_name":
+		return &a.ProjectName
+	case "description":
+		return &a.Description
+	case "tech_stack":
+		return &a.TechStack
+	case "features":
+		return &a.Features
+	case "out_of_scope":
+		return &a.OutOfScope
+	case "success_criteria":
+		return &a.SuccessCrit
+	case "constraints":
+		return &a.Constraints
+	}
+	return nil
+}
+
+// Get returns the answer for key.
+func (a *Answers) Get(key string) string {
+	if f := a.field(key); f != nil {
+		return *f
+	}
+	return a.Extra[key]
+}
+
+// Set records the answer for key.
+func (a *Answers) Set(key, value string) {
+	if f := a.field(key); f != nil {
+		*f = value
+		return
+	}
+	if a.Extra == nil {
+		a.Extra = map[string]string{}
+	}
+	a.Extra[key] = value
+}
+
+// Options says how Claude is invoked for generation.
+type Options struct {
+	// Model is the Claude model to use; empty leaves it to claude.
+	Model string
+	// Dir is the directory claude runs in; empty is the current directory.
+	Dir string
+}
+
+// Generate calls Claude to expand rough notes into a structured PRD, using
+// the prompt of the answers' template.
+func Generate(a Answers, opts Options) (string, error) {
+	t := a.Template
+	if t == nil {
+		var err error
+		if t, err = LoadTemplate(DefaultTemplate); err != nil {
+			return "", err
+		}
+	}
+	prompt, err := t.prompt(a)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	return ask(prompt, opts)
+}
+
+// ask runs a one-shot prompt through claude and returns its output.
+func ask(prompt string, opts Options) (string, error) {
+	args := []string{"-p", prompt}
+	if opts.Model != "" {
+		args = append(args, "--model", opts.Model)
+	}
+	cmd := exec.Command("claude", args...)
+	cmd.Dir = opts.Dir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -36,21 +122,3 @@ func Generate(a Answers) (string, error) {
 	}
 	return stdout.String(), nil
 }
-
-func formatNotes(a Answers) string {
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("Project: %s\n", a.ProjectName))
-	b.WriteString(fmt.Sprintf("Description: %s\n", a.Description))
-	b.WriteString(fmt.Sprintf("Tech Stack: %s\n", a.TechStack))
-	b.WriteString(fmt.Sprintf("Core Features:\n%s\n", a.Features))
-	if a.OutOfScope != "" {
-		b.WriteString(fmt.Sprintf("Out of Scope:\n%s\n", a.OutOfScope))
-	}
-	if a.SuccessCrit != "" {
-		b.WriteString(fmt.Sprintf("Success Criteria:\n%s\n", a.SuccessCrit))
-	}
-	if a.Constraints != "" {
-		b.WriteString(fmt.Sprintf("Constraints:\n%s\n", a.Constraints))
-	}
-	return b.String()
-}
@@ -0,0 +1,586 @@
+package prd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Import formats.
+const (
+	FormatGitHub = "github" // gh issue view --json title,body,...
+	FormatJira   = "jira"   // Jira XML (RSS) or JSON issue export
+	FormatText   = "text"   // freeform text or markdown
+)
+
+// Imported is a spec read from an issue tracker export or a text file.
+type Imported struct {
+	Title string
+	// Ref says where the spec came from: an issue URL or key.
+	Ref    string
+	Labels []string
+	// Description is the body with tasks and acceptance criteria taken out.
+	Description string
+	Tasks       []ImportedItem
+	Criteria    []ImportedItem
+}
+
+// ImportedItem is a task or acceptance criterion.
+type ImportedItem struct {
+	Text string
+	Done bool
+	// Depth is the list nesting level, 0 for top-level items.
+	Depth int
+}
+
+// DetectFormat guesses the format of an export.
+func DetectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case isJiraXML(data):
+		return FormatJira
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var probe map[string]json.RawMessage
+		if json.Unmarshal(trimmed, &probe) == nil {
+			if _, ok := probe["fields"]; ok {
+				return FormatJira
+			}
+			if _, ok := probe["issues"]; ok {
+				return FormatJira
+			}
+			if _, ok := probe["body"]; ok {
+				return FormatGitHub
+			}
+		}
+	}
+	return FormatText
+}
+
+// Import reads an export in the given format, detecting it if format is
+// empty.
+func Import(data []byte, format string) (*Imported, error) {
+	if format == "" {
+		format = DetectFormat(data)
+	}
+	switch format {
+	case FormatGitHub:
+		return importGitHub(data)
+	case FormatJira:
+		if isJiraXML(data) {
+			return importJiraXML(data)
+		}
+		return importJiraJSON(data)
+	case FormatText:
+		return importText(string(data)), nil
+	default:
+		return nil, fmt.Errorf("unknown import format %q (want github, jira or text)", format)
+	}
+}
+
+// isJiraXML reports whether data is an RSS export, as Jira's XML view is:
+// its first element, after any XML declaration and comments, is <rss> or
+// <item>. HTML or markdown that happens to start with a tag is not.
+func isJiraXML(data []byte) bool {
+	rest := bytes.TrimSpace(data)
+	for {
+		switch {
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end := bytes.Index(rest, []byte("?>"))
+			if end < 0 {
+				return false
+			}
+			rest = bytes.TrimSpace(rest[end+2:])
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end := bytes.Index(rest, []byte("-->"))
+			if end < 0 {
+				return false
+			}
+			rest = bytes.TrimSpace(rest[end+3:])
+		default:
+			return bytes.HasPrefix(rest, []byte("<rss")) || bytes.HasPrefix(rest, []byte("<item"))
+		}
+	}
+}
+
+func importGitHub(data []byte) (*Imported, error) {
+	var issue struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Body   string `json:"body"`
+		URL    string `json:"url"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return nil, fmt.Errorf("parsing GitHub issue: %w", err)
+	}
+	im := &Imported{Title: issue.Title, Ref: issue.URL}
+	if im.Ref == "" && issue.Number > 0 {
+		im.Ref = fmt.Sprintf("issue #%d", issue.Number)
+	}
+	for _, l := range issue.Labels {
+		im.Labels = append(im.Labels, l.Name)
+	}
+	im.splitBody(issue.Body)
+	return im, nil
+}
+
+func importJiraXML(data []byte) (*Imported, error) {
+	var rss struct {
+		Items []struct {
+			Title        string   `xml:"title"`
+			Link         string   `xml:"link"`
+			Key          string   `xml:"key"`
+			Summary      string   `xml:"summary"`
+			Description  string   `xml:"description"`
+			Labels       []string `xml:"labels>label"`
+			CustomFields []struct {
+				Name   string   `xml:"customfieldname"`
+				Values []string `xml:"customfieldvalues>customfieldvalue"`
+			} `xml:"customfields>customfield"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(data, &rss); err != nil {
+		return nil, fmt.Errorf("parsing Jira XML: %w", err)
+	}
+	if len(rss.Items) != 1 {
+		return nil, fmt.Errorf("Jira export has %d issues; export one issue at a time", len(rss.Items))
+	}
+	item := rss.Items[0]
+	im := &Imported{Title: item.Summary, Ref: item.Link, Labels: item.Labels}
+	if im.Title == "" {
+		im.Title = item.Title
+	}
+	if im.Ref == "" {
+		im.Ref = item.Key
+	}
+	body := htmlToMarkdown(item.Description)
+	for _, f := range item.CustomFields {
+		if acceptanceTitleRe.MatchString(f.Name) {
+			body += "\n\n## Acceptance Criteria\n\n" + htmlToMarkdown(strings.Join(f.Values, "\n"))
+		}
+	}
+	im.splitBody(body)
+	return im, nil
+}
+
+func importJiraJSON(data []byte) (*Imported, error) {
+	type issue struct {
+		Key    string                     `json:"key"`
+		Self   string                     `json:"self"`
+		Fields map[string]json.RawMessage `json:"fields"`
+		Names  map[string]string          `json:"names"`
+	}
+	var is issue
+	if err := json.Unmarshal(data, &is); err != nil {
+		return nil, fmt.Errorf("parsing Jira JSON: %w", err)
+	}
+	if is.Fields == nil {
+		// A search result: {"issues": [...]}.
+		var search struct {
+			Issues []issue           `json:"issues"`
+			Names  map[string]string `json:"names"`
+		}
+		if err := json.Unmarshal(data, &search); err != nil {
+			return nil, fmt.Errorf("parsing Jira JSON: %w", err)
+		}
+		if len(search.Issues) != 1 {
+			return nil, fmt.Errorf("Jira export has %d issues; export one issue at a time", len(search.Issues))
+		}
+		is = search.Issues[0]
+		if is.Names == nil {
+			is.Names = search.Names
+		}
+	}
+
+	im := &Imported{Ref: is.Key}
+	_ = json.Unmarshal(is.Fields["summary"], &im.Title)
+	_ = json.Unmarshal(is.Fields["labels"], &im.Labels)
+	body := jiraText(is.Fields["description"])
+	for id, raw := range is.Fields {
+		name := is.Names[id]
+		if name == "" {
+			name = id
+		}
+		if acceptanceTitleRe.MatchString(name) {
+			if text := jiraText(raw); text != "" {
+				body += "\n\n## Acceptance Criteria\n\n" + text
+			}
+		}
+	}
+	im.splitBody(body)
+	return im, nil
+}
+
+// jiraText converts a Jira field value to markdown. Cloud (API v3) uses
+// Atlassian Document Format; Server and API v2 use wiki markup strings.
+func jiraText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return wikiToMarkdown(s)
+	}
+	var doc adfNode
+	if json.Unmarshal(raw, &doc) == nil && doc.Type != "" {
+		var b strings.Builder
+		doc.render(&b, 0)
+		return strings.TrimSpace(b.String())
+	}
+	return ""
+}
+
+// adfNode is a node of an Atlassian Document Format document.
+type adfNode struct {
+	Type    string         `json:"type"`
+	Text    string         `json:"text"`
+	Attrs   map[string]any `json:"attrs"`
+	Content []adfNode      `json:"content"`
+}
+
+func (n adfNode) render(b *strings.Builder, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch n.Type {
+	case "text":
+		b.WriteString(n.Text)
+	case "hardBreak":
+		b.WriteString("\n")
+	case "paragraph":
+		n.renderChildren(b, indent)
+		b.WriteString("\n\n")
+	case "heading":
+		level, _ := n.Attrs["level"].(float64)
+		b.WriteString(strings.Repeat("#", max(1, int(level))) + " ")
+		n.renderChildren(b, indent)
+		b.WriteString("\n\n")
+	case "bulletList", "orderedList", "taskList":
+		for _, item := range n.Content {
+			item.render(b, indent)
+		}
+		if indent == 0 {
+			b.WriteString("\n")
+		}
+	case "listItem", "taskItem":
+		marker := "- "
+		if n.Type == "taskItem" {
+			marker = "- [ ] "
+			if state, _ := n.Attrs["state"].(string); state == "DONE" {
+				marker = "- [x] "
+			}
+		}
+		b.WriteString(pad + marker)
+		for _, c := range n.Content {
+			switch c.Type {
+			case "bulletList", "orderedList", "taskList":
+				b.WriteString("\n")
+				c.render(b, indent+1)
+			case "paragraph":
+				c.renderChildren(b, indent)
+			default:
+				c.render(b, indent)
+			}
+		}
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	case "codeBlock":
+		b.WriteString("```\n")
+		n.renderChildren(b, indent)
+		b.WriteString("\n```\n\n")
+	default:
+		n.renderChildren(b, indent)
+	}
+}
+
+func (n adfNode) renderChildren(b *strings.Builder, indent int) {
+	for _, c := range n.Content {
+		c.render(b, indent)
+	}
+}
+
+var (
+	wikiHeadingRe = regexp.MustCompile(`^h([1-6])\.\s+`)
+	wikiBulletRe  = regexp.MustCompile(`^([*#-]+)\s+`)
+)
+
+// wikiToMarkdown converts the common parts of Jira wiki markup: headings,
+// lists and code blocks.
+func wikiToMarkdown(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if m := wikiHeadingRe.FindStringSubmatch(line); m != nil {
+			lines[i] = strings.Repeat("#", int(m[1][0]-'0')) + " " + line[len(m[0]):]
+		} else if m := wikiBulletRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, "- [") {
+			marker := "- "
+			if strings.HasSuffix(m[1], "#") {
+				marker = "1. "
+			}
+			lines[i] = strings.Repeat("  ", len(m[1])-1) + marker + line[len(m[0]):]
+		} else if strings.HasPrefix(strings.TrimSpace(line), "{code") || strings.HasPrefix(strings.TrimSpace(line), "{noformat") {
+			lines[i] = "```"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	htmlBlockRe = regexp.MustCompile(`(?i)</?(p|div|ul|ol|br|tr|table)[^>]*>`)
+	htmlItemRe  = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlHeadRe  = regexp.MustCompile(`(?i)<h([1-6])[^>]*>`)
+	htmlTagRe   = regexp.MustCompile(`<[^>]+>`)
+	blankRunRe  = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToMarkdown turns the HTML of a Jira XML export into rough markdown:
+// list items become bullets, headings keep their level, and other tags are
+// dropped.
+func htmlToMarkdown(s string) string {
+	s = htmlItemRe.ReplaceAllString(s, "\n- ")
+	s = htmlHeadRe.ReplaceAllStringFunc(s, func(m string) string {
+		return "\n\n" + strings.Repeat("#", int(m[2]-'0')) + " "
+	})
+	s = htmlBlockRe.ReplaceAllString(s, "\n")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankRunRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// importText reads a freeform spec. The first line is the title.
+func importText(s string) *Imported {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	im := &Imported{}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if t := strings.TrimSpace(line); t != "" {
+			im.Title = strings.TrimSpace(strings.TrimLeft(t, "#"))
+			s = strings.Join(lines[i+1:], "\n")
+			break
+		}
+	}
+	im.splitBody(s)
+	return im
+}
+
+// boldHeadingRe matches headings written as a bold line or a short line
+// ending in a colon, as issue bodies often do.
+var boldHeadingRe = regexp.MustCompile(`^\*\*(.+?):?\*\*:?\s*$|^([A-Z][\w /-]{2,40}):\s*$`)
+
+// splitBody takes checkbox tasks and acceptance criteria out of a
+// markdown body, leaving the rest as the description. In acceptance
+// criteria sections only list items are criteria; prose and code blocks
+// stay in the description. Headings left with nothing under them are
+// dropped.
+func (im *Imported) splitBody(body string) {
+	// heading marks a line of desc that is a heading: its level (7 for bold
+	// lines) and whether anything was taken out from under it.
+	type heading struct {
+		level     int
+		extracted bool
+	}
+	var desc []string
+	headings := map[int]*heading{}
+	var open []*heading // headings enclosing the current line
+	extract := func() {
+		for _, h := range open {
+			h.extracted = true
+		}
+	}
+
+	inCriteria, inFence := false, false
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if fenceRe.MatchString(line) {
+			inFence = !inFence
+		}
+		if inFence || fenceRe.MatchString(line) {
+			desc = append(desc, line)
+			continue
+		}
+
+		title, level := "", 7
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			title, level = m[2], len(m[1])
+		} else if m := boldHeadingRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			title = m[1] + m[2]
+			// A plain "Something:" line in a criteria section usually
+			// introduces the list rather than starting a new section.
+			if inCriteria && m[2] != "" && !acceptanceTitleRe.MatchString(title) {
+				title = ""
+			}
+		}
+		if title != "" {
+			inCriteria = acceptanceTitleRe.MatchString(title)
+			for len(open) > 0 && open[len(open)-1].level >= level {
+				open = open[:len(open)-1]
+			}
+			h := &heading{level: level}
+			open = append(open, h)
+			headings[len(desc)] = h
+			desc = append(desc, line)
+			continue
+		}
+
+		if m := taskRe.FindStringSubmatch(line); m != nil {
+			item := ImportedItem{Text: strings.TrimSpace(m[4]), Done: m[3] != " ", Depth: depth(m[1])}
+			if inCriteria {
+				im.Criteria = append(im.Criteria, item)
+			} else {
+				im.Tasks = append(im.Tasks, item)
+			}
+			extract()
+			continue
+		}
+		if m := listItemRe.FindStringSubmatch(line); m != nil && inCriteria {
+			im.Criteria = append(im.Criteria, ImportedItem{Text: strings.TrimSpace(m[3]), Depth: depth(m[1])})
+			extract()
+			continue
+		}
+		desc = append(desc, line)
+	}
+
+	// Walk backwards so a heading knows whether its subsections were kept.
+	kept := make([]bool, len(desc))
+	for i := len(desc) - 1; i >= 0; i-- {
+		h := headings[i]
+		if h == nil {
+			kept[i] = strings.TrimSpace(desc[i]) != ""
+			continue
+		}
+		kept[i] = !h.extracted
+		for j := i + 1; j < len(desc) && !kept[i]; j++ {
+			if next := headings[j]; next != nil && next.level <= h.level {
+				break
+			}
+			kept[i] = kept[j]
+		}
+	}
+	var out []string
+	for i, line := range desc {
+		if headings[i] == nil || kept[i] {
+			out = append(out, line)
+		}
+	}
+	im.Description = strings.TrimSpace(blankRunRe.ReplaceAllString(strings.Join(out, "\n"), "\n\n"))
+}
+
+// Markdown renders the spec as a ralphkit PRD.
+func (im *Imported) Markdown() string {
+	var b strings.Builder
+	title := im.Title
+	if title == "" {
+		title = "Imported spec"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	if im.Ref != "" {
+		fmt.Fprintf(&b, "Imported from %s.\n", im.Ref)
+	}
+	if len(im.Labels) > 0 {
+		fmt.Fprintf(&b, "Labels: %s\n", strings.Join(im.Labels, ", "))
+	}
+	if im.Ref != "" || len(im.Labels) > 0 {
+		b.WriteString("\n")
+	}
+	if im.Description != "" {
+		b.WriteString("## Overview\n\n")
+		b.WriteString(demoteHeadings(im.Description))
+		b.WriteString("\n\n")
+	}
+	writeItems(&b, "Tasks", im.Tasks)
+	writeItems(&b, "Acceptance Criteria", im.Criteria)
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// FileName suggests a file name for the PRD, from its title.
+func (im *Imported) FileName() string {
+	return slug(im.Title) + ".prd.md"
+}
+
+// Answers turns the spec into wizard answers, for expanding it into a
+// full PRD with Generate.
+func (im *Imported) Answers() Answers {
+	a := Answers{ProjectName: im.Title, Description: im.Description}
+	if a.Description == "" {
+		a.Description = im.Title
+	}
+	var features []string
+	pad := indents(im.Tasks)
+	for i, t := range im.Tasks {
+		features = append(features, pad[i]+"- "+t.Text)
+	}
+	a.Features = strings.Join(features, "\n")
+	var criteria []string
+	pad = indents(im.Criteria)
+	for i, c := range im.Criteria {
+		criteria = append(criteria, pad[i]+"- "+c.Text)
+	}
+	a.SuccessCrit = strings.Join(criteria, "\n")
+	return a
+}
+
+func writeItems(b *strings.Builder, title string, items []ImportedItem) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "## %s\n\n", title)
+	pad := indents(items)
+	for i, it := range items {
+		mark := " "
+		if it.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(b, "%s- [%s] %s\n", pad[i], mark, it.Text)
+	}
+	b.WriteString("\n")
+}
+
+// indents returns the indentation for each item's nesting level. An item is
+// nested at most one level below the item before it, since its original
+// parent may have stayed behind in the description.
+func indents(items []ImportedItem) []string {
+	out := make([]string, len(items))
+	prev := -1
+	for i, it := range items {
+		prev = min(it.Depth, prev+1)
+		out[i] = strings.Repeat("  ", prev)
+	}
+	return out
+}
+
+// demoteHeadings shifts headings in the description so the highest of
+// them sits just below the PRD's own level-2 sections.
+func demoteHeadings(s string) string {
+	lines := strings.Split(s, "\n")
+	top := 0
+	forEachHeading(lines, func(i, level int, title string) {
+		if top == 0 || level < top {
+			top = level
+		}
+	})
+	if top == 0 || top >= 3 {
+		return s
+	}
+	forEachHeading(lines, func(i, level int, title string) {
+		lines[i] = strings.Repeat("#", min(level+3-top, 6)) + " " + title
+	})
+	return strings.Join(lines, "\n")
+}
+
+// forEachHeading calls fn for each markdown heading outside code blocks.
+func forEachHeading(lines []string, fn func(i, level int, title string)) {
+	inFence := false
+	for i, line := range lines {
+		if fenceRe.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil && !inFence {
+			fn(i, len(m[1]), m[2])
+		}
+	}
+}
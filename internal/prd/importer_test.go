@@ -0,0 +1,88 @@
+package prd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestImportFixtures imports each export in testdata/import and compares
+// the PRD with its .prd.md golden file.
+func TestImportFixtures(t *testing.T) {
+	tests := []struct {
+		file, format string
+	}{
+		{"github.json", FormatGitHub},
+		{"jira.xml", FormatJira},
+		{"jira-adf.json", FormatJira},
+		{"jira-wiki.json", FormatJira},
+		{"text.md", FormatText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "import", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := DetectFormat(data); got != tt.format {
+				t.Errorf("DetectFormat = %q, want %q", got, tt.format)
+			}
+			im, err := Import(data, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := im.Markdown()
+
+			golden := filepath.Join("testdata", "import", tt.file+".prd.md")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("imported PRD differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestSplitBody(t *testing.T) {
+	im := &Imported{}
+	im.splitBody("Intro.\n\n## Acceptance Criteria\n\nChecked in CI:\n\n```\nmake check\n```\n\n- Builds\n  - on Linux\n\n### Tasks\n- [ ] Do it\n\n## Notes\nLater.")
+
+	want := "Intro.\n\n## Acceptance Criteria\n\nChecked in CI:\n\n```\nmake check\n```\n\n## Notes\nLater."
+	if im.Description != want {
+		t.Errorf("Description = %q, want %q", im.Description, want)
+	}
+	if len(im.Criteria) != 2 || im.Criteria[0] != (ImportedItem{Text: "Builds"}) || im.Criteria[1] != (ImportedItem{Text: "on Linux", Depth: 1}) {
+		t.Errorf("Criteria = %+v", im.Criteria)
+	}
+	if len(im.Tasks) != 1 || im.Tasks[0].Text != "Do it" {
+		t.Errorf("Tasks = %+v", im.Tasks)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"<rss version=\"0.92\"><channel/></rss>":                                         FormatJira,
+		"<?xml version=\"1.0\"?>\n<!-- RSS generated by JIRA -->\n<rss><channel/></rss>": FormatJira,
+		"<item><title>x</title></item>":                                                  FormatJira,
+		"<p>An HTML spec</p>":                                                            FormatText,
+		"<!-- draft -->\n# Spec\n":                                                       FormatText,
+		"{\"fields\": {}}":                                                               FormatJira,
+		"{\"body\": \"\"}":                                                               FormatGitHub,
+		"# Spec":                                                                         FormatText,
+	}
+	for in, want := range tests {
+		if got := DetectFormat([]byte(in)); got != want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package prd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity ranks lint findings.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Lint rule IDs.
+const (
+	RuleNoTasks             = "no-tasks"
+	RuleNoAcceptance        = "no-acceptance-criteria"
+	RuleFeatureNoCheckboxes = "feature-without-checkboxes"
+	RuleVagueCriterion      = "vague-criterion"
+	RuleMissingOutOfScope   = "missing-out-of-scope"
+	RuleScopeTooLarge       = "scope-too-large"
+	RuleDuplicateTask       = "duplicate-task"
+	RuleDuplicateID         = "duplicate-id"
+)
+
+// defaultTasksPerIteration is the scope check's guess at how many tasks one
+// iteration gets through.
+const defaultTasksPerIteration = 3
+
+// Finding is a problem found by Lint.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line,omitempty"` // 0 for document-wide findings
+	Message  string   `json:"message"`
+}
+
+// LintOptions tunes Lint.
+type LintOptions struct {
+	// MaxIterations is the loop's iteration limit; 0 skips the scope check.
+	MaxIterations int
+	// TasksPerIteration is how many tasks an iteration is expected to finish
+	// (default 3).
+	TasksPerIteration int
+}
+
+// vagueRe matches words that make a criterion impossible to check. Words
+// with a checkable everyday sense, such as "clean" or "simple", are left out.
+var vagueRe = regexp.MustCompile(`(?i)\b(fast|nice(ly)?|better|easy|easily|intuitive(ly)?|user[\s-]friendly|robust|scalable|performant|seamless(ly)?|modern|beautiful|elegant|appropriate(ly)?|reasonabl[ey]|efficient(ly)?)\b`)
+
+// Lint checks a PRD for problems that tend to make the loop run forever:
+// nothing to tick off, criteria nobody can verify, or more work than the
+// iteration limit allows.
+func Lint(d *Document, opts LintOptions) []Finding {
+	var out []Finding
+	add := func(rule string, sev Severity, line int, format string, args ...any) {
+		out = append(out, Finding{Rule: rule, Severity: sev, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(d.Tasks) == 0 {
+		add(RuleNoTasks, SeverityWarning, 0, "no checklist items (- [ ] ...); the loop can't measure progress and only stops when the agent says it's done")
+	} else if len(d.AcceptanceCriteria()) == 0 {
+		add(RuleNoAcceptance, SeverityWarning, 0, "no acceptance criteria; add checkboxes under each feature or an Acceptance Criteria section")
+	}
+
+	for _, f := range d.Features {
+		if len(f.Criteria) == 0 {
+			add(RuleFeatureNoCheckboxes, SeverityWarning, f.Line, "feature %q has no checkboxes, so nobody can tell when it's done", f.Name)
+		}
+	}
+
+	for _, t := range d.AcceptanceCriteria() {
+		if words := vagueWords(t.Text); len(words) > 0 {
+			add(RuleVagueCriterion, SeverityWarning, t.Line, "%q is vague (%s); state something measurable", t.Text, strings.Join(words, ", "))
+		}
+	}
+
+	if !d.hasOutOfScope() {
+		add(RuleMissingOutOfScope, SeverityInfo, 0, "no Out of Scope / Non-Goals section; the agent may keep extending the work")
+	}
+
+	if opts.MaxIterations > 0 {
+		per := opts.TasksPerIteration
+		if per <= 0 {
+			per = defaultTasksPerIteration
+		}
+		if n := len(d.Remaining()); n > opts.MaxIterations*per {
+			add(RuleScopeTooLarge, SeverityWarning, 0, "%d open tasks is a lot for %d iterations (about %d per iteration); split the PRD or raise max_iterations", n, opts.MaxIterations, per)
+		}
+	}
+
+	texts := map[string]*Task{}
+	ids := map[string]*Task{}
+	for _, t := range d.Tasks {
+		if t.writtenID != "" {
+			if first, ok := ids[t.writtenID]; ok {
+				add(RuleDuplicateID, SeverityError, t.Line, "task ID %q is already used on line %d", t.writtenID, first.Line)
+			} else {
+				ids[t.writtenID] = t
+			}
+		}
+		key := strings.Join(strings.Fields(strings.ToLower(t.Text)), " ")
+		if first, ok := texts[key]; ok {
+			add(RuleDuplicateTask, SeverityWarning, t.Line, "duplicate of the task on line %d", first.Line)
+		} else {
+			texts[key] = t
+		}
+	}
+	return out
+}
+
+func vagueWords(text string) []string {
+	var words []string
+	seen := map[string]bool{}
+	for _, w := range vagueRe.FindAllString(text, -1) {
+		w = strings.ToLower(w)
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func (d *Document) hasOutOfScope() bool {
+	var walk func([]*Section) bool
+	walk = func(ss []*Section) bool {
+		for _, s := range ss {
+			if outOfScopeTitleRe.MatchString(s.Title) || walk(s.Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(d.Sections)
+}
+
+// HasErrors reports whether any finding is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
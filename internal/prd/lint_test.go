@@ -0,0 +1,121 @@
+package prd
+
+import (
+	"strings"
+	"testing"
+)
+
+// good is a PRD that no rule should flag.
+const good = `# Todo CLI
+
+## Features
+
+### Add items
+- [ ] ` + "`todo add milk`" + ` prints "added" and exits 0
+- [ ] A clean exit leaves no lock file behind
+
+## Out of Scope
+- Sync
+`
+
+// findingsFor returns the findings of one rule for src.
+func findingsFor(rule, src string, opts LintOptions) []Finding {
+	var out []Finding
+	for _, f := range Lint(Parse(src), opts) {
+		if f.Rule == rule {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		severity Severity
+		flagged  string // a PRD the rule flags
+		clean    string // a similar PRD it doesn't
+		opts     LintOptions
+	}{
+		{
+			rule:     RuleNoTasks,
+			severity: SeverityWarning,
+			flagged:  "# Notes\n\nBuild a thing.\n",
+			clean:    "# Notes\n\n- [ ] Build a thing\n",
+		},
+		{
+			rule:     RuleNoAcceptance,
+			severity: SeverityWarning,
+			flagged:  "# T\n\n## Tasks\n- [ ] Write the parser\n",
+			clean:    "# T\n\n## Acceptance Criteria\n- [ ] `go test ./...` passes\n",
+		},
+		{
+			rule:     RuleFeatureNoCheckboxes,
+			severity: SeverityWarning,
+			flagged:  "# T\n\n## Features\n\n### Export\n\nExports lists.\n\n### Import\n- [ ] Imports CSV\n",
+			clean:    "# T\n\n## Features\n\n### Export\n- [ ] Exports CSV\n\n### Import\n- [ ] Imports CSV\n",
+		},
+		{
+			rule:     RuleVagueCriterion,
+			severity: SeverityWarning,
+			flagged:  "# T\n\n## Acceptance Criteria\n- [ ] The UI is intuitive and fast\n",
+			clean:    "# T\n\n## Tasks\n- [ ] Make the UI nice\n\n## Acceptance Criteria\n- [ ] `make clean` removes build/, a simple good exit, etc.\n",
+		},
+		{
+			rule:     RuleMissingOutOfScope,
+			severity: SeverityInfo,
+			flagged:  "# T\n\n- [ ] One\n",
+			clean:    "# T\n\n- [ ] One\n\n## Non-Goals\n- Two\n",
+		},
+		{
+			rule:     RuleScopeTooLarge,
+			severity: SeverityWarning,
+			flagged:  "# T\n" + strings.Repeat("- [ ] task\n", 4),
+			clean:    "# T\n- [ ] a\n- [ ] b\n- [ ] c\n",
+			opts:     LintOptions{MaxIterations: 1},
+		},
+		{
+			rule:     RuleDuplicateTask,
+			severity: SeverityWarning,
+			flagged:  "# T\n- [ ] Add  login\n- [ ] add login\n",
+			clean:    "# T\n- [ ] Add login\n- [ ] Add logout\n",
+		},
+		{
+			rule:     RuleDuplicateID,
+			severity: SeverityError,
+			flagged:  "# T\n- [ ] One <!-- id: a -->\n- [ ] Two <!-- id: a -->\n",
+			clean:    "# T\n- [ ] One <!-- id: a -->\n- [ ] Two <!-- id: b -->\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got := findingsFor(tt.rule, tt.flagged, tt.opts)
+			if len(got) != 1 {
+				t.Fatalf("flagged PRD: got %d %s findings, want 1: %+v", len(got), tt.rule, got)
+			}
+			if got[0].Severity != tt.severity {
+				t.Errorf("severity = %s, want %s", got[0].Severity, tt.severity)
+			}
+			if got := findingsFor(tt.rule, tt.clean, tt.opts); len(got) != 0 {
+				t.Errorf("clean PRD: unexpected findings %+v", got)
+			}
+			if got := findingsFor(tt.rule, good, LintOptions{MaxIterations: 10}); len(got) != 0 {
+				t.Errorf("good PRD: unexpected findings %+v", got)
+			}
+		})
+	}
+}
+
+func TestLintVagueWordsAreListed(t *testing.T) {
+	got := findingsFor(RuleVagueCriterion, "## Acceptance Criteria\n- [ ] Robust, ROBUST and user-friendly\n", LintOptions{})
+	if len(got) != 1 || !strings.Contains(got[0].Message, "(robust, user-friendly)") {
+		t.Errorf("findings = %+v", got)
+	}
+}
+
+func TestLintNoTasksBlocksOnlyWhenStrict(t *testing.T) {
+	findings := Lint(Parse("# Notes\n\nBuild a thing.\n"), LintOptions{})
+	if HasErrors(findings) {
+		t.Errorf("a PRD without checkboxes should not block the run: %+v", findings)
+	}
+}
@@ -0,0 +1,406 @@
+package prd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Document is a parsed PRD. It keeps the source lines, so writing it back
+// reproduces the file byte for byte apart from deliberate edits such as
+// ticking a task or assigning IDs.
+type Document struct {
+	// Title is the first level-1 heading, or the first heading if there is
+	// no level-1 heading.
+	Title string
+	// Sections are the top-level sections in document order.
+	Sections []*Section
+	// Tasks are all checklist items in document order.
+	Tasks []*Task
+	// Features are the subsections (or top-level list items) of the
+	// Features section.
+	Features []*Feature
+	// OutOfScope lists the items of Out of Scope / Non-Goals sections.
+	OutOfScope []string
+	// Plan is set for plans written by 'ralphkit plan', which carry the
+	// PlanMarker. Each plan step says how to verify it, so all its tasks
+	// count as acceptance criteria.
+	Plan bool
+
+	lines []string
+	// cr marks the lines that ended in "\r\n", so mixed line endings
+	// survive a round trip.
+	cr []bool
+}
+
+// Section is a heading and everything up to the next heading of the same or
+// a higher level.
+type Section struct {
+	Title    string
+	Level    int
+	Line     int // 1-based line of the heading
+	Children []*Section
+	Tasks    []*Task // checklist items directly in this section
+
+	parent *Section
+}
+
+// Task is a checklist item ("- [ ] ..." or "- [x] ...").
+type Task struct {
+	// ID identifies the task across edits: the explicit <!-- id: ... -->
+	// comment if present, otherwise derived from the text.
+	ID   string
+	Text string
+	Done bool
+	Line int // 1-based
+	// Depth is the list nesting level, 0 for top-level items.
+	Depth int
+	// Section is the innermost section containing the task.
+	Section *Section
+	// Feature is the feature the task belongs to, if any.
+	Feature *Feature
+	// Acceptance is set for acceptance criteria: tasks in a feature or under
+	// an acceptance/success criteria heading.
+	Acceptance bool
+
+	// writtenID is the ID in the task's <!-- id: ... --> comment, if any.
+	// ID differs from it when another task already uses it.
+	writtenID string
+}
+
+// Feature is a feature of the PRD with its acceptance criteria.
+type Feature struct {
+	Name     string
+	Line     int
+	Criteria []*Task
+}
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fenceRe     = regexp.MustCompile("^\\s*(```|~~~)")
+	taskRe      = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+	listItemRe  = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	idCommentRe = regexp.MustCompile(`\s*<!--\s*id:\s*([A-Za-z0-9._-]+)\s*-->\s*$`)
+
+	featuresTitleRe   = regexp.MustCompile(`(?i)^(core\s+|key\s+)?features?\b|^requirements\b|^user stories\b`)
+	acceptanceTitleRe = regexp.MustCompile(`(?i)acceptance|success criteria|definition of done`)
+	outOfScopeTitleRe = regexp.MustCompile(`(?i)out[\s-]of[\s-]scope|non[\s-]?goals|not in scope`)
+)
+
+// PlanMarker is the comment that marks a document as a plan.
+const PlanMarker = "<!-- ralphkit:plan -->"
+
+// ParseFile reads and parses a PRD file.
+func ParseFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data)), nil
+}
+
+// Parse parses PRD markdown. Any text is a valid PRD; parts that aren't
+// recognised are simply kept as they are.
+func Parse(src string) *Document {
+	d := &Document{lines: strings.Split(src, "\n")}
+	d.cr = make([]bool, len(d.lines))
+	for i, line := range d.lines[:len(d.lines)-1] {
+		if strings.HasSuffix(line, "\r") {
+			d.lines[i], d.cr[i] = line[:len(line)-1], true
+		}
+	}
+	d.parse()
+	return d
+}
+
+func (d *Document) parse() {
+	var stack []*Section
+	var feature *Feature
+	// featureLevel is the heading level of the Features section while inside
+	// it, or 0.
+	featureLevel := 0
+	outOfScopeLevel := 0
+	inFence := false
+	sawH1 := false
+
+	for i, line := range d.lines {
+		if fenceRe.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if strings.TrimSpace(line) == PlanMarker {
+			d.Plan = true
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			s := &Section{Title: m[2], Level: level, Line: i + 1}
+			for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				d.Sections = append(d.Sections, s)
+			} else {
+				s.parent = stack[len(stack)-1]
+				s.parent.Children = append(s.parent.Children, s)
+			}
+			stack = append(stack, s)
+			if (level == 1 && !sawH1) || d.Title == "" {
+				d.Title = s.Title
+				sawH1 = sawH1 || level == 1
+			}
+
+			if featureLevel > 0 && level <= featureLevel {
+				featureLevel = 0
+			}
+			if outOfScopeLevel > 0 && level <= outOfScopeLevel {
+				outOfScopeLevel = 0
+			}
+			feature = nil
+			switch {
+			case featureLevel > 0 && level == featureLevel+1:
+				feature = &Feature{Name: s.Title, Line: i + 1}
+				d.Features = append(d.Features, feature)
+			case featureLevel > 0 && len(d.Features) > 0:
+				// A deeper heading stays within the current feature.
+				feature = d.Features[len(d.Features)-1]
+			case featuresTitleRe.MatchString(s.Title):
+				featureLevel = level
+			}
+			if outOfScopeTitleRe.MatchString(s.Title) {
+				outOfScopeLevel = level
+			}
+			continue
+		}
+
+		var section *Section
+		if len(stack) > 0 {
+			section = stack[len(stack)-1]
+		}
+
+		if m := taskRe.FindStringSubmatch(line); m != nil {
+			t := &Task{
+				Done:    m[3] != " ",
+				Line:    i + 1,
+				Depth:   depth(m[1]),
+				Section: section,
+			}
+			t.Text = m[4]
+			if id := idCommentRe.FindStringSubmatch(t.Text); id != nil {
+				t.ID, t.writtenID = id[1], id[1]
+				t.Text = strings.TrimSpace(t.Text[:len(t.Text)-len(id[0])])
+			}
+			t.Text = strings.TrimSpace(t.Text)
+
+			// In a flat Features list, each top-level item is a feature.
+			if featureLevel > 0 && section != nil && section.Level == featureLevel && t.Depth == 0 {
+				feature = &Feature{Name: t.Text, Line: i + 1}
+				d.Features = append(d.Features, feature)
+			}
+			if feature != nil {
+				t.Feature = feature
+				feature.Criteria = append(feature.Criteria, t)
+			}
+			t.Acceptance = feature != nil || inAcceptanceSection(section)
+			if section != nil {
+				section.Tasks = append(section.Tasks, t)
+			}
+			d.Tasks = append(d.Tasks, t)
+			continue
+		}
+
+		if m := listItemRe.FindStringSubmatch(line); m != nil {
+			text := strings.TrimSpace(m[3])
+			if featureLevel > 0 && section != nil && section.Level == featureLevel && depth(m[1]) == 0 {
+				feature = &Feature{Name: strings.Trim(text, "*_ "), Line: i + 1}
+				d.Features = append(d.Features, feature)
+			}
+			if outOfScopeLevel > 0 && depth(m[1]) == 0 {
+				d.OutOfScope = append(d.OutOfScope, text)
+			}
+		}
+	}
+	if d.Plan {
+		for _, t := range d.Tasks {
+			t.Acceptance = true
+		}
+	}
+	d.assignIDs()
+}
+
+// assignIDs gives every task a unique ID. Written IDs are claimed first, so
+// an ID derived from the text never takes one that a later task spells out.
+// A task repeating an earlier task's written ID, or whose derived ID is
+// taken, gets a numeric suffix.
+func (d *Document) assignIDs() {
+	used := map[string]bool{}
+	first := map[*Task]bool{}
+	for _, t := range d.Tasks {
+		if t.writtenID != "" && !used[t.writtenID] {
+			used[t.writtenID] = true
+			first[t] = true
+		}
+	}
+	for _, t := range d.Tasks {
+		if first[t] {
+			t.ID = t.writtenID
+			continue
+		}
+		base := t.writtenID
+		if base == "" {
+			base = slug(t.Text)
+		}
+		t.ID = base
+		for n := 2; used[t.ID]; n++ {
+			t.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[t.ID] = true
+	}
+}
+
+func inAcceptanceSection(s *Section) bool {
+	for ; s != nil; s = s.parent {
+		if acceptanceTitleRe.MatchString(s.Title) {
+			return true
+		}
+	}
+	return false
+}
+
+// depth converts list indentation to a nesting level, treating a tab or
+// two spaces as one level.
+func depth(indent string) int {
+	n := 0
+	for _, r := range indent {
+		if r == '\t' {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n / 2
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// maxSlug bounds derived task IDs.
+const maxSlug = 40
+
+func slug(text string) string {
+	s := nonSlug.ReplaceAllString(strings.ToLower(text), "-")
+	s = strings.Trim(s, "-")
+	if len(s) > maxSlug {
+		s = strings.TrimRight(s[:maxSlug], "-")
+	}
+	if s == "" {
+		s = "task"
+	}
+	return s
+}
+
+// String returns the document as markdown, preserving everything that
+// wasn't edited.
+func (d *Document) String() string {
+	var b strings.Builder
+	for i, line := range d.lines {
+		b.WriteString(line)
+		if d.cr[i] {
+			b.WriteString("\r")
+		}
+		if i < len(d.lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// WriteFile writes the document to path.
+func (d *Document) WriteFile(path string) error {
+	return os.WriteFile(path, []byte(d.String()), 0o644)
+}
+
+// Task returns the task with the given ID, or nil.
+func (d *Document) Task(id string) *Task {
+	for _, t := range d.Tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Progress counts done and total tasks.
+func (d *Document) Progress() (done, total int) {
+	for _, t := range d.Tasks {
+		if t.Done {
+			done++
+		}
+	}
+	return done, len(d.Tasks)
+}
+
+// Remaining returns the tasks not yet done, in document order.
+func (d *Document) Remaining() []*Task {
+	var out []*Task
+	for _, t := range d.Tasks {
+		if !t.Done {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// AcceptanceCriteria returns the tasks that are acceptance criteria.
+func (d *Document) AcceptanceCriteria() []*Task {
+	var out []*Task
+	for _, t := range d.Tasks {
+		if t.Acceptance {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+var checkboxRe = regexp.MustCompile(`\[([ xX])\]`)
+
+// SetDone ticks or unticks a task in the document.
+func (d *Document) SetDone(t *Task, done bool) {
+	mark := "[ ]"
+	if done {
+		mark = "[x]"
+	}
+	i := t.Line - 1
+	loc := checkboxRe.FindStringIndex(d.lines[i])
+	if loc == nil {
+		return
+	}
+	d.lines[i] = d.lines[i][:loc[0]] + mark + d.lines[i][loc[1]:]
+	t.Done = done
+}
+
+// AssignIDs writes an explicit ID comment for every task whose ID isn't
+// already written out, including tasks that repeat another task's ID, so
+// IDs stay stable when the task text is later edited. It returns how many
+// tasks were changed.
+func (d *Document) AssignIDs() int {
+	n := 0
+	for _, t := range d.Tasks {
+		if t.writtenID == t.ID {
+			continue
+		}
+		i := t.Line - 1
+		line := d.lines[i]
+		if loc := idCommentRe.FindStringIndex(line); loc != nil {
+			line = line[:loc[0]]
+		}
+		d.lines[i] = strings.TrimRight(line, " \t") + " <!-- id: " + t.ID + " -->"
+		t.writtenID = t.ID
+		n++
+	}
+	return n
+}
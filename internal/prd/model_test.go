@@ -0,0 +1,103 @@
+package prd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStringRoundTrip(t *testing.T) {
+	tests := map[string]string{
+		"empty":               "",
+		"newline only":        "\n",
+		"no trailing newline": "# Title\n\n- [ ] a task",
+		"blank lines at end":  "# Title\n\n- [ ] a task\n\n\n",
+		"crlf":                "# Title\r\n\r\n## Tasks\r\n- [ ] one\r\n- [x] two\r\n",
+		"mixed line endings":  "# Title\r\n\n## Tasks\n- [ ] one\r\n",
+		"trailing spaces":     "# Title   \n- [ ] spaced   \n\t- [ ] tabbed\t\n",
+		"closing hashes":      "## Features ##\n### Login #\n- [ ] works\n",
+		"fence":               "# T\n```md\n- [ ] not a task\n# not a heading\n```\n- [ ] real\n",
+		"ids":                 "- [ ] one <!-- id: a1 -->\n- [X] two <!--id:b-2-->\n",
+		"unicode":             "# Überblick ✓\n- [ ] café — naïve\n",
+		"plain text":          "just some notes\nwith no structure",
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Parse(src).String(); got != src {
+				t.Errorf("round trip changed the document:\n got %q\nwant %q", got, src)
+			}
+		})
+	}
+}
+
+func TestSetDoneOnlyTouchesCheckbox(t *testing.T) {
+	src := "# T\r\n\r\n- [ ] first [ ] literal\r\n- [x] second\r\n"
+	d := Parse(src)
+	d.SetDone(d.Tasks[0], true)
+	d.SetDone(d.Tasks[1], false)
+	want := "# T\r\n\r\n- [x] first [ ] literal\r\n- [ ] second\r\n"
+	if got := d.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAssignIDsKeepsIDsAcrossRewording(t *testing.T) {
+	d := Parse("## Tasks\n- [ ] Add login\n- [ ] Add login\n- [ ] Keep <!-- id: keep -->\n")
+	if n := d.AssignIDs(); n != 2 {
+		t.Fatalf("AssignIDs changed %d tasks, want 2", n)
+	}
+	want := "## Tasks\n- [ ] Add login <!-- id: add-login -->\n- [ ] Add login <!-- id: add-login-2 -->\n- [ ] Keep <!-- id: keep -->\n"
+	if got := d.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if n := Parse(want).AssignIDs(); n != 0 {
+		t.Errorf("AssignIDs on an already pinned document changed %d tasks", n)
+	}
+
+	reworded := strings.Replace(want, "Add login <!-- id: add-login-2", "Add OAuth login <!-- id: add-login-2", 1)
+	if task := Parse(reworded).Task("add-login-2"); task == nil || task.Text != "Add OAuth login" {
+		t.Errorf("reworded task not found by its ID: %+v", task)
+	}
+}
+
+func TestPlanStepsAreCriteria(t *testing.T) {
+	steps := "# Setup guide\n\n## Steps\n- [ ] Install the CLI\n"
+	if d := Parse(steps); d.Plan || len(d.AcceptanceCriteria()) != 0 {
+		t.Errorf("a Steps section outside a plan counted as acceptance criteria")
+	}
+
+	p := &Plan{Source: "todo.prd.md", Title: "Todo", Steps: []Step{{ID: "s1", Title: "Add the model", Verify: "go test ./..."}}}
+	d := Parse(p.Markdown())
+	if !d.Plan {
+		t.Fatal("plan markdown not recognised as a plan")
+	}
+	if d.Title != "Plan: Todo" {
+		t.Errorf("title = %q", d.Title)
+	}
+	if got := d.AcceptanceCriteria(); len(got) != 1 || got[0].ID != "s1" {
+		t.Errorf("plan criteria = %+v", got)
+	}
+}
+
+func TestIDsAvoidWrittenIDs(t *testing.T) {
+	src := "- [ ] Foo\n- [ ] Foo\n- [ ] Bar <!-- id: foo-2 -->\n- [ ] Baz <!-- id: x -->\n- [ ] Qux <!-- id: x -->\n"
+	d := Parse(src)
+	var got []string
+	for _, task := range d.Tasks {
+		got = append(got, task.ID)
+	}
+	if want := "foo foo-3 foo-2 x x-2"; strings.Join(got, " ") != want {
+		t.Fatalf("IDs = %v, want %s", got, want)
+	}
+
+	if n := d.AssignIDs(); n != 3 {
+		t.Errorf("AssignIDs changed %d tasks, want 3", n)
+	}
+	want := "- [ ] Foo <!-- id: foo -->\n- [ ] Foo <!-- id: foo-3 -->\n- [ ] Bar <!-- id: foo-2 -->\n- [ ] Baz <!-- id: x -->\n- [ ] Qux <!-- id: x-2 -->\n"
+	if got := d.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	again := Parse(want)
+	if n := again.AssignIDs(); n != 0 {
+		t.Errorf("IDs moved on the next run: %d tasks changed", n)
+	}
+}
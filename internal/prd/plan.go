@@ -0,0 +1,138 @@
+package prd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Step is one task of a plan.
+type Step struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Verify says how to check the step is done, e.g. a command to run.
+	Verify string `json:"verify"`
+}
+
+// Plan is a PRD broken down into small, ordered steps.
+type Plan struct {
+	// Source is the path of the PRD, relative to the plan file.
+	Source string
+	Title  string
+	Steps  []Step
+}
+
+const planPrompt = `You are a tech lead planning work for an autonomous coding agent that will implement a PRD one step at a time.
+
+Break the PRD below into small, ordered steps. Each step should be doable in one short agent session and leave the project building and its tests passing. Put steps in an order where every step only depends on earlier ones. Give each step a way to verify it is done, preferably a command to run. The current directory holds the code the PRD is for; look at it so the steps build on what already exists.
+
+Output ONLY a JSON array, no prose and no code fence, in this form:
+[{"id": "s1", "title": "Short imperative description", "depends_on": [], "verify": "How to check it, e.g. go test ./internal/store passes"}]
+
+PRD:
+%s`
+
+// GeneratePlan asks Claude to break the PRD at prdPath, whose contents are
+// spec, into an ordered plan. opts.Dir should be the code the plan is for,
+// so Claude can look at it.
+func GeneratePlan(spec, prdPath string, opts Options) (*Plan, error) {
+	source := filepath.Base(prdPath)
+	if abs, err := filepath.Abs(prdPath); err == nil {
+		if rel, err := filepath.Rel(filepath.Dir(PlanPath(abs)), abs); err == nil {
+			source = filepath.ToSlash(rel)
+		}
+	}
+	out, err := ask(fmt.Sprintf(planPrompt, spec), opts)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := parseSteps(out)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{Source: source, Title: Parse(spec).Title, Steps: steps}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseSteps extracts the JSON step list from the agent's answer, which
+// may wrap it in prose or a code fence despite being asked not to.
+func parseSteps(out string) ([]Step, error) {
+	start, end := strings.Index(out, "["), strings.LastIndex(out, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no step list in Claude's answer")
+	}
+	var steps []Step
+	if err := json.Unmarshal([]byte(out[start:end+1]), &steps); err != nil {
+		return nil, fmt.Errorf("parsing steps: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("Claude returned an empty plan")
+	}
+	return steps, nil
+}
+
+// validate checks that step IDs are unique and that steps only depend on
+// earlier ones, filling in missing IDs.
+func (p *Plan) validate() error {
+	seen := map[string]bool{}
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		s.ID = slug(s.ID)
+		if s.ID == "task" {
+			s.ID = fmt.Sprintf("s%d", i+1)
+		}
+		if strings.TrimSpace(s.Title) == "" {
+			return fmt.Errorf("step %s has no title", s.ID)
+		}
+		if seen[s.ID] {
+			return fmt.Errorf("duplicate step ID %q", s.ID)
+		}
+		for j, dep := range s.DependsOn {
+			dep = slug(dep)
+			if !seen[dep] {
+				return fmt.Errorf("step %s depends on %q, which doesn't come before it", s.ID, dep)
+			}
+			s.DependsOn[j] = dep
+		}
+		seen[s.ID] = true
+	}
+	return nil
+}
+
+// Markdown renders the plan as a checklist that ralphkit run can work
+// through like any PRD.
+func (p *Plan) Markdown() string {
+	var b strings.Builder
+	title := p.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(p.Source), filepath.Ext(p.Source))
+	}
+	fmt.Fprintf(&b, "%s\n# Plan: %s\n\n", PlanMarker, title)
+	fmt.Fprintf(&b, "Implementation plan for [%s](%s). Read it for the full requirements; work through the steps in order.\n\n", p.Source, p.Source)
+	b.WriteString("## Steps\n\n")
+	for _, s := range p.Steps {
+		fmt.Fprintf(&b, "- [ ] %s <!-- id: %s -->\n", strings.TrimSpace(s.Title), s.ID)
+		deps := "none"
+		if len(s.DependsOn) > 0 {
+			deps = strings.Join(s.DependsOn, ", ")
+		}
+		fmt.Fprintf(&b, "  - Depends on: %s\n", deps)
+		if v := strings.TrimSpace(s.Verify); v != "" {
+			fmt.Fprintf(&b, "  - Verify: %s\n", v)
+		}
+	}
+	return b.String()
+}
+
+// PlanPath returns where the plan for a PRD is kept: next to it, named
+// <name>.plan.md.
+func PlanPath(prdPath string) string {
+	base := strings.TrimSuffix(prdPath, filepath.Ext(prdPath))
+	base = strings.TrimSuffix(base, ".prd")
+	return base + ".plan.md"
+}
@@ -0,0 +1,101 @@
+package prd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxQuestions is how many clarifying questions Claude may ask about
+// a draft unless told otherwise.
+const DefaultMaxQuestions = 5
+
+const questionsPrompt = `Review this draft PRD as the engineer who will have to implement it. Find the
+gaps that would force you to guess: ambiguous requirements, missing edge
+cases, unclear acceptance criteria, undecided technical choices.
+
+Ask at most %d clarifying questions, most important first. Each question must
+be answerable in a sentence or two. Ask nothing the draft already answers. If
+the draft is clear enough, return an empty array.
+
+Output ONLY a JSON array of strings, no prose and no code fence.
+
+Draft PRD:
+%s`
+
+const revisePrompt = `Revise this PRD. Keep its structure and everything that is not affected by
+the changes below; only rewrite what they call for. Keep markdown checkboxes
+for tasks and acceptance criteria. Output ONLY the revised PRD, no preamble.
+
+%s
+
+Current PRD:
+%s`
+
+// Clarification is a question Claude asked about a draft and the user's
+// answer.
+type Clarification struct {
+	Question string
+	Answer   string
+}
+
+// Questions asks Claude to review a draft PRD and returns up to max
+// clarifying questions about it. None means the draft is clear enough.
+func Questions(draft string, max int, opts Options) ([]string, error) {
+	if max <= 0 {
+		max = DefaultMaxQuestions
+	}
+	out, err := ask(fmt.Sprintf(questionsPrompt, max, draft), opts)
+	if err != nil {
+		return nil, err
+	}
+	qs, err := parseQuestions(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(qs) > max {
+		qs = qs[:max]
+	}
+	return qs, nil
+}
+
+// parseQuestions extracts the JSON question list from the agent's answer,
+// which may wrap it in prose or a code fence despite being asked not to.
+func parseQuestions(out string) ([]string, error) {
+	start, end := strings.Index(out, "["), strings.LastIndex(out, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no question list in Claude's answer")
+	}
+	var raw []string
+	if err := json.Unmarshal([]byte(out[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("parsing questions: %w", err)
+	}
+	var qs []string
+	for _, q := range raw {
+		if q = strings.TrimSpace(q); q != "" {
+			qs = append(qs, q)
+		}
+	}
+	return qs, nil
+}
+
+// Clarify revises a draft PRD using the answers to Claude's questions.
+// Unanswered questions are left for Claude to decide.
+func Clarify(draft string, cs []Clarification, opts Options) (string, error) {
+	var b strings.Builder
+	b.WriteString("The author answered these questions about the draft. Work the answers into\nthe PRD wherever they belong:\n")
+	for _, c := range cs {
+		if c.Answer == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\nQ: %s\nA: %s\n", c.Question, c.Answer)
+	}
+	return ask(fmt.Sprintf(revisePrompt, b.String(), draft), opts)
+}
+
+// Revise applies freeform change requests, such as "split feature 3" or
+// "drop analytics", to a draft PRD.
+func Revise(draft, feedback string, opts Options) (string, error) {
+	changes := "Changes requested by the author:\n" + feedback
+	return ask(fmt.Sprintf(revisePrompt, changes, draft), opts)
+}
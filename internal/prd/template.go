@@ -0,0 +1,231 @@
+package prd
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/kfroemming/ralphkit/internal/paths"
+	"go.yaml.in/yaml/v3"
+)
+
+// DefaultTemplate is the template used when none is chosen.
+const DefaultTemplate = "default"
+
+//go:embed templates/*.yaml
+var builtinTemplates embed.FS
+
+// Template is a kind of PRD: the questions the wizard asks for it and the
+// prompt that turns the answers into a PRD.
+type Template struct {
+	// Name is the file name without extension.
+	Name        string     `yaml:"-"`
+	Title       string     `yaml:"title"`
+	Description string     `yaml:"description"`
+	Questions   []Question `yaml:"questions"`
+	// Prompt is a text/template. It gets .Project, .Notes (every answer,
+	// labelled), .Values (answers by key) and .Repo (the repository summary,
+	// if any). Unless the prompt uses .Repo, the summary is appended.
+	Prompt string `yaml:"prompt"`
+	// Path is the file the template was loaded from, empty for built-in
+	// templates.
+	Path string `yaml:"-"`
+}
+
+// Question is a wizard question. Its key is either one of the Answers
+// fields (description, tech_stack, features, out_of_scope,
+// success_criteria, constraints) or a template-specific key.
+type Question struct {
+	Key         string `yaml:"key"`
+	Label       string `yaml:"label"` // how the answer is labelled in the notes
+	Title       string `yaml:"title"` // what the wizard asks
+	Placeholder string `yaml:"placeholder"`
+	Multiline   bool   `yaml:"multiline"`
+	Required    bool   `yaml:"required"`
+}
+
+// TemplateDir returns the directory of user templates,
+// ~/.ralphkit/prd-templates.
+func TemplateDir() (string, error) {
+	home, err := paths.Home()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "prd-templates"), nil
+}
+
+// Templates returns the built-in templates and the user's, sorted by name.
+// A user template replaces the built-in one of the same name.
+func Templates() ([]*Template, error) {
+	byName := map[string]*Template{}
+	entries, err := builtinTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := builtinTemplates.ReadFile("templates/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseTemplate(e.Name(), data)
+		if err != nil {
+			return nil, fmt.Errorf("built-in template %s: %w", e.Name(), err)
+		}
+		byName[t.Name] = t
+	}
+
+	dir, err := TemplateDir()
+	if err != nil {
+		return nil, err
+	}
+	userEntries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range userEntries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseTemplate(e.Name(), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		t.Path = path
+		byName[t.Name] = t
+	}
+
+	out := make([]*Template, 0, len(byName))
+	for _, t := range byName {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// LoadTemplate returns the named template.
+func LoadTemplate(name string) (*Template, error) {
+	all, err := Templates()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(all))
+	for i, t := range all {
+		if t.Name == name {
+			return t, nil
+		}
+		names[i] = t.Name
+	}
+	return nil, fmt.Errorf("unknown template %q (have %s)", name, strings.Join(names, ", "))
+}
+
+func parseTemplate(file string, data []byte) (*Template, error) {
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	t.Name = strings.TrimSuffix(file, filepath.Ext(file))
+	if t.Title == "" {
+		t.Title = t.Name
+	}
+	if strings.TrimSpace(t.Prompt) == "" {
+		return nil, fmt.Errorf("template %s has no prompt", t.Name)
+	}
+	if _, err := template.New(t.Name).Parse(t.Prompt); err != nil {
+		return nil, fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	seen := map[string]bool{}
+	for i := range t.Questions {
+		q := &t.Questions[i]
+		if q.Key == "" || seen[q.Key] {
+			return nil, fmt.Errorf("template %s: question %d needs a unique key", t.Name, i+1)
+		}
+		seen[q.Key] = true
+		if q.Title == "" {
+			q.Title = q.Key
+		}
+		if q.Label == "" {
+			q.Label = q.Title
+		}
+	}
+	return &t, nil
+}
+
+// prompt renders the generation prompt for a set of answers.
+func (t *Template) prompt(a Answers) (string, error) {
+	values := map[string]string{"project_name": a.ProjectName}
+	for _, q := range t.Questions {
+		values[q.Key] = a.Get(q.Key)
+	}
+	for k, v := range a.Extra {
+		values[k] = v
+	}
+	tmpl, err := template.New(t.Name).Parse(t.Prompt)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, struct {
+		Project string
+		Notes   string
+		Values  map[string]string
+		Repo    string
+	}{a.ProjectName, t.notes(a), values, a.RepoContext})
+	if err != nil {
+		return "", err
+	}
+	if a.RepoContext != "" && !strings.Contains(t.Prompt, ".Repo") {
+		b.WriteString("\n\nThe PRD will be carried out in this existing repository. Base the technical approach on its language, layout and conventions, name real packages and files where it helps, and phrase acceptance criteria in terms of its test setup:\n\n")
+		b.WriteString(a.RepoContext)
+	}
+	return b.String(), nil
+}
+
+// notes lists the answers under their labels, in question order, followed
+// by answers to questions the template doesn't ask.
+func (t *Template) notes(a Answers) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project: %s\n", a.ProjectName)
+	asked := map[string]bool{}
+	for _, q := range t.Questions {
+		asked[q.Key] = true
+		writeNote(&b, q.Label, a.Get(q.Key), q.Multiline)
+	}
+	for _, key := range answerKeys {
+		if !asked[key] {
+			writeNote(&b, answerLabels[key], a.Get(key), true)
+		}
+	}
+	var extra []string
+	for k := range a.Extra {
+		if !asked[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		writeNote(&b, k, a.Extra[k], true)
+	}
+	return b.String()
+}
+
+func writeNote(b *strings.Builder, label, value string, multiline bool) {
+	if value == "" {
+		return
+	}
+	if multiline {
+		fmt.Fprintf(b, "%s:\n%s\n", label, value)
+	} else {
+		fmt.Fprintf(b, "%s: %s\n", label, value)
+	}
+}
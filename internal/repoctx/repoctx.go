@@ -0,0 +1,208 @@
+// Package repoctx summarises a git repository for prompts, so generated
+// PRDs fit the code they will be carried out in.
+package repoctx
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kfroemming/ralphkit/internal/detect"
+	"github.com/kfroemming/ralphkit/internal/paths"
+)
+
+// Limits keep the summary small enough to sit in a prompt.
+const (
+	maxTreeLines   = 60
+	maxReadmeBytes = 3000
+	maxTestDirs    = 10
+	maxCommits     = 15
+)
+
+// Context is a summary of a repository.
+type Context struct {
+	Root        string
+	ProjectType detect.ProjectType
+	// TestCommand is how ralphkit will run the tests, if it knows.
+	TestCommand string
+	// Files is the number of tracked files.
+	Files int
+	// Tree lists directories with their file counts, two levels deep.
+	Tree string
+	// README is the start of the README.
+	README string
+	// Tests describes where the tests are.
+	Tests string
+	// GitLog is the recent history, one commit per line.
+	GitLog string
+}
+
+// Gather summarises the git repository containing dir. It returns nil if
+// dir is not inside one.
+func Gather(dir string) (*Context, error) {
+	root := paths.RepoRoot(dir)
+	if root == "" {
+		return nil, nil
+	}
+	c := &Context{Root: root, ProjectType: detect.Detect(root)}
+	if bin, args := detect.TestCommand(c.ProjectType); bin != "" {
+		c.TestCommand = strings.TrimSpace(bin + " " + strings.Join(args, " "))
+	}
+
+	out, err := git(root, "ls-files")
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\n") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	c.Files = len(files)
+	c.Tree = tree(files)
+	c.Tests = testLayout(files)
+	c.README = readme(root, files)
+	// A repository without commits has no log; that's fine.
+	c.GitLog, _ = git(root, "log", "--oneline", "--no-decorate", fmt.Sprintf("-n%d", maxCommits))
+	return c, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// tree lists top-level files and the directories two levels deep with how
+// many files each holds.
+func tree(files []string) string {
+	counts := map[string]int{}
+	var top []string
+	for _, f := range files {
+		parts := strings.Split(f, "/")
+		if len(parts) == 1 {
+			top = append(top, f)
+			continue
+		}
+		counts[parts[0]+"/"]++
+		if len(parts) > 2 {
+			counts[parts[0]+"/"+parts[1]+"/"]++
+		}
+	}
+	dirs := make([]string, 0, len(counts))
+	for d := range counts {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	var lines []string
+	for _, d := range dirs {
+		indent := ""
+		if strings.Count(d, "/") == 2 {
+			indent = "  "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s (%s)", indent, d, plural(counts[d], "file")))
+	}
+	lines = append(lines, top...)
+	if len(lines) > maxTreeLines {
+		n := len(lines) - maxTreeLines
+		lines = append(lines[:maxTreeLines], fmt.Sprintf("... and %d more", n))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var testFileRe = regexp.MustCompile(`(_test\.go|\.(test|spec)\.[cm]?[jt]sx?|(^|/)test_[^/]*\.py|_test\.py|Test\.java|_spec\.rb)$`)
+
+// testLayout says how many test files there are and where.
+func testLayout(files []string) string {
+	byDir := map[string][]string{}
+	n := 0
+	for _, f := range files {
+		if !testFileRe.MatchString(f) {
+			continue
+		}
+		n++
+		dir := path.Dir(f)
+		byDir[dir] = append(byDir[dir], path.Base(f))
+	}
+	if n == 0 {
+		return "No test files found."
+	}
+	dirs := make([]string, 0, len(byDir))
+	for d := range byDir {
+		dirs = append(dirs, d)
+	}
+	// Directories with the most tests first.
+	sort.Slice(dirs, func(i, j int) bool {
+		if a, b := len(byDir[dirs[i]]), len(byDir[dirs[j]]); a != b {
+			return a > b
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	lines := []string{fmt.Sprintf("%d test files in %d directories, for example:", n, len(dirs))}
+	for i, d := range dirs {
+		if i == maxTestDirs {
+			lines = append(lines, fmt.Sprintf("... and %d more directories", len(dirs)-i))
+			break
+		}
+		names := byDir[d]
+		example := names[0]
+		if len(names) > 1 {
+			example += fmt.Sprintf(" and %d more", len(names)-1)
+		}
+		lines = append(lines, fmt.Sprintf("%s/: %s", d, example))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// readme returns the start of the top-level README, if there is one.
+func readme(root string, files []string) string {
+	for _, f := range files {
+		if strings.Contains(f, "/") || !strings.HasPrefix(strings.ToLower(f), "readme") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, f))
+		if err != nil {
+			return ""
+		}
+		s := string(data)
+		if len(s) > maxReadmeBytes {
+			s = s[:maxReadmeBytes] + "\n..."
+		}
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+// String renders the summary for a prompt.
+func (c *Context) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project type: %s\n", c.ProjectType)
+	if c.TestCommand != "" {
+		fmt.Fprintf(&b, "Test command: %s\n", c.TestCommand)
+	}
+	fmt.Fprintf(&b, "\nLayout (%d tracked files):\n%s\n", c.Files, c.Tree)
+	fmt.Fprintf(&b, "\nTests:\n%s\n", c.Tests)
+	if c.README != "" {
+		fmt.Fprintf(&b, "\nREADME (start):\n%s\n", c.README)
+	}
+	if c.GitLog != "" {
+		fmt.Fprintf(&b, "\nRecent commits:\n%s\n", c.GitLog)
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kfroemming/ralphkit/internal/ui"
+)
+
+//go:embed report.html
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": ui.FormatDuration,
+	"time":     func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"clock":    func(t time.Time) string { return t.Format("15:04:05") },
+	"diffLines": func(diff string) []diffLine {
+		var lines []diffLine
+		for _, l := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+			lines = append(lines, diffLine{Text: l, Class: diffClass(l)})
+		}
+		return lines
+	},
+	// percent scales v against the largest value for bar widths.
+	"percent": func(v, max time.Duration) int {
+		if max <= 0 {
+			return 0
+		}
+		return int(v * 100 / max)
+	},
+}).Parse(htmlSource))
+
+type diffLine struct {
+	Text, Class string
+}
+
+func diffClass(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "diff "):
+		return "file"
+	case strings.HasPrefix(line, "@@"):
+		return "hunk"
+	case strings.HasPrefix(line, "+"):
+		return "add"
+	case strings.HasPrefix(line, "-"):
+		return "del"
+	default:
+		return ""
+	}
+}
+
+// WriteHTML writes the report as a self-contained HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	var longest time.Duration
+	for _, it := range r.Iterations {
+		longest = max(longest, it.Duration)
+	}
+	return htmlTemplate.Execute(w, struct {
+		*Report
+		Longest time.Duration
+	}{r, longest})
+}
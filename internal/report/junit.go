@@ -0,0 +1,152 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JUnit test case granularities.
+const (
+	CasesIteration = "iteration" // one test case per loop iteration
+	CasesCriteria  = "criteria"  // one test case per PRD checklist item
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML with test cases per iteration
+// or per PRD checklist item, as chosen by cases.
+func (r *Report) WriteJUnit(w io.Writer, cases string) error {
+	s := r.Session
+	suite := junitSuite{
+		Name:      "ralphkit." + s.Name,
+		Time:      fmt.Sprintf("%.3f", r.Duration().Seconds()),
+		Timestamp: s.StartTime.Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{"session", s.Name},
+			{"model", s.Model},
+			{"status", s.Status},
+			{"reason", s.Reason},
+			{"iterations", fmt.Sprintf("%d/%d", s.Iterations, s.MaxIterations)},
+			{"prd", s.PRDFile},
+			{"workDir", s.WorkDir},
+		},
+	}
+	switch cases {
+	case CasesIteration, "":
+		suite.Cases = r.iterationCases()
+	case CasesCriteria:
+		suite.Cases = r.criteriaCases()
+	default:
+		return fmt.Errorf("unknown JUnit case mode %q (want iteration or criteria)", cases)
+	}
+	for _, c := range suite.Cases {
+		suite.Tests++
+		if c.Failure != nil {
+			suite.Failures++
+		}
+		if c.Error != nil {
+			suite.Errors++
+		}
+	}
+	doc := junitSuites{
+		Name:     "ralphkit",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (r *Report) iterationCases() []junitCase {
+	classname := "ralphkit." + r.Session.Name
+	var cases []junitCase
+	for _, it := range r.Iterations {
+		c := junitCase{
+			Name:      fmt.Sprintf("iteration %d", it.Number),
+			Classname: classname,
+			Time:      fmt.Sprintf("%.3f", it.Duration.Seconds()),
+			SystemOut: it.DiffStat,
+		}
+		switch {
+		case it.AgentStatus == "error":
+			c.Error = &junitProblem{Message: "agent failed", Type: it.AgentClass, Body: it.AgentError}
+		case it.Tests == "failed":
+			c.Failure = &junitProblem{Message: "tests failed", Body: it.TestOutput}
+		}
+		if len(it.Notes) > 0 {
+			c.SystemOut = strings.TrimSpace("Operator notes:\n- " + strings.Join(it.Notes, "\n- ") + "\n\n" + c.SystemOut)
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+func (r *Report) criteriaCases() []junitCase {
+	var cases []junitCase
+	for _, t := range r.Checklist {
+		group := "prd"
+		if t.Feature != nil {
+			group = t.Feature.Name
+		} else if t.Section != nil {
+			group = t.Section.Title
+		}
+		c := junitCase{Name: t.Text, Classname: "ralphkit." + r.Session.Name + "." + group, Time: "0"}
+		if !t.Done {
+			c.Failure = &junitProblem{Message: "not checked off in the PRD"}
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
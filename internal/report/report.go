@@ -0,0 +1,206 @@
+// Package report turns a session's state, event log and log file into JUnit
+// XML and HTML reports.
+package report
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kfroemming/ralphkit/internal/prd"
+	"github.com/kfroemming/ralphkit/internal/session"
+)
+
+// maxDiff caps the diff kept per iteration so reports stay readable.
+const maxDiff = 64 * 1024
+
+// maxTestOutput is how many trailing lines of test output are kept per
+// iteration.
+const maxTestOutput = 60
+
+// Report is everything known about one run.
+type Report struct {
+	Session    *session.State
+	Iterations []*Iteration
+	// Checklist is the PRD's tasks as they are now.
+	Checklist []*prd.Task
+	Generated time.Time
+}
+
+// Iteration is one pass of the loop.
+type Iteration struct {
+	Number   int
+	Start    time.Time
+	Duration time.Duration
+
+	AgentStatus   string // ok or error
+	AgentClass    string
+	AgentError    string
+	AgentDuration time.Duration
+
+	Tests         string // passed, failed or "" if tests did not run
+	TestsDuration time.Duration
+	TestOutput    string
+
+	Notes []string
+
+	DiffStat string
+	Diff     string
+	// DiffTruncated is set when Diff was cut at maxDiff.
+	DiffTruncated bool
+}
+
+// Build collects the report for s.
+func Build(s *session.State) (*Report, error) {
+	events, err := session.Events(s)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Session: s, Generated: time.Now()}
+	byNumber := map[int]*Iteration{}
+	var current *Iteration
+	var snapshot string
+	closeIteration := func(at time.Time) {
+		if current != nil && current.Duration == 0 {
+			current.Duration = at.Sub(current.Start)
+		}
+	}
+	for _, e := range events {
+		switch e.Type {
+		case session.EventStart:
+			snapshot = e.Snapshot
+		case session.EventIteration:
+			closeIteration(e.Time)
+			current = &Iteration{Number: e.Iteration, Start: e.Time}
+			byNumber[e.Iteration] = current
+			r.Iterations = append(r.Iterations, current)
+		case session.EventNote:
+			if it := byNumber[e.Iteration]; it != nil {
+				it.Notes = append(it.Notes, e.Message)
+			}
+		case session.EventAgent:
+			it := byNumber[e.Iteration]
+			if it == nil {
+				continue
+			}
+			it.AgentStatus = e.Status
+			it.AgentClass = e.Class
+			it.AgentError = e.Message
+			it.AgentDuration = seconds(e.Duration)
+			if snapshot != "" && e.Snapshot != "" && snapshot != e.Snapshot {
+				it.DiffStat = git(s.WorkDir, "diff", "--stat", snapshot, e.Snapshot)
+				it.Diff = git(s.WorkDir, "diff", snapshot, e.Snapshot)
+				if len(it.Diff) > maxDiff {
+					it.Diff = it.Diff[:maxDiff]
+					it.DiffTruncated = true
+				}
+			}
+			if e.Snapshot != "" {
+				snapshot = e.Snapshot
+			}
+		case session.EventTests:
+			if it := byNumber[e.Iteration]; it != nil {
+				it.Tests = e.Status
+				it.TestsDuration = seconds(e.Duration)
+			}
+		case session.EventEnd:
+			closeIteration(e.Time)
+			current = nil
+		}
+	}
+	if current != nil && s.Status == "running" {
+		current.Duration = time.Since(current.Start)
+	}
+
+	for n, out := range testOutput(s.LogFile) {
+		if it := byNumber[n]; it != nil {
+			it.TestOutput = out
+		}
+	}
+	if doc, err := prd.ParseFile(s.PRDFile); err == nil {
+		r.Checklist = doc.Tasks
+	}
+	return r, nil
+}
+
+// TestsPassed and TestsFailed count iterations by test outcome.
+func (r *Report) TestsPassed() int { return r.countTests("passed") }
+
+func (r *Report) TestsFailed() int { return r.countTests("failed") }
+
+func (r *Report) countTests(status string) int {
+	n := 0
+	for _, it := range r.Iterations {
+		if it.Tests == status {
+			n++
+		}
+	}
+	return n
+}
+
+// ChecklistDone counts checked PRD items.
+func (r *Report) ChecklistDone() int {
+	n := 0
+	for _, item := range r.Checklist {
+		if item.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// Duration is the wall time of the run so far.
+func (r *Report) Duration() time.Duration {
+	if r.Session.EndTime != nil {
+		return r.Session.EndTime.Sub(r.Session.StartTime)
+	}
+	return time.Since(r.Session.StartTime)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func git(dir string, args ...string) string {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// testOutput returns the tail of each iteration's tests section of the log.
+func testOutput(logFile string) map[int]string {
+	f, err := os.Open(logFile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	sections := map[int][]string{}
+	inTests, iteration := false, 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if section, n, ok := session.ParseMarker(line); ok {
+			inTests, iteration = section == session.SectionTests, n
+			continue
+		}
+		if inTests {
+			lines := append(sections[iteration], line)
+			if len(lines) > maxTestOutput {
+				lines = lines[1:]
+			}
+			sections[iteration] = lines
+		}
+	}
+	out := make(map[int]string, len(sections))
+	for n, lines := range sections {
+		out[n] = strings.Join(lines, "\n")
+	}
+	return out
+}
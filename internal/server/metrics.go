@@ -0,0 +1,248 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kfroemming/ralphkit/internal/session"
+)
+
+// durationBuckets are the histogram upper bounds, in seconds, for iteration,
+// agent and test durations.
+var durationBuckets = []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// sessionStatuses are reported for every session so each series is either
+// 0 or 1 rather than appearing and disappearing.
+var sessionStatuses = []string{"running", "paused", "stopped", "complete", "crashed"}
+
+// tokenTypes are the type label values of ralphkit_tokens_total.
+var tokenTypes = []string{"input", "output", "cache_read", "cache_write"}
+
+// handleMetrics exports loop activity in the Prometheus text format. The
+// numbers are derived from session state and event logs on every scrape, so
+// they cover every session in the stores, not just those seen while serving.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	sessions, err := session.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m := newMetricSet()
+	for _, s := range sessions {
+		events, err := session.Events(s)
+		if err != nil {
+			continue
+		}
+		m.addSession(s, events)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+type metric struct {
+	name, help, kind string
+	// samples maps a rendered label set to its value.
+	samples map[string]float64
+	// histograms maps a rendered label set to its observations.
+	histograms map[string][]float64
+}
+
+type metricSet struct {
+	metrics []*metric
+	byName  map[string]*metric
+}
+
+func newMetricSet() *metricSet {
+	m := &metricSet{byName: map[string]*metric{}}
+	m.define("ralphkit_session_status", "gauge", "Current status of a session (1 for the active status, 0 otherwise).")
+	m.define("ralphkit_session_iterations", "gauge", "Iterations a session has started.")
+	m.define("ralphkit_session_max_iterations", "gauge", "Iteration limit of a session.")
+	m.define("ralphkit_session_tasks_done", "gauge", "Checked PRD items in a session.")
+	m.define("ralphkit_session_tasks_total", "gauge", "Total PRD checklist items in a session.")
+	m.define("ralphkit_iterations_total", "counter", "Iterations started.")
+	m.define("ralphkit_iteration_duration_seconds", "histogram", "Wall time of finished iterations.")
+	m.define("ralphkit_agent_runs_total", "counter", "Agent invocations that finished.")
+	m.define("ralphkit_agent_errors_total", "counter", "Agent invocations that failed, by class (start, exit, signal, other).")
+	m.define("ralphkit_agent_duration_seconds", "histogram", "Wall time of agent invocations.")
+	m.define("ralphkit_tokens_total", "counter", "Tokens used by the agent, by type (input, output, cache_read, cache_write).")
+	m.define("ralphkit_cost_usd_total", "counter", "Agent spend in US dollars, as reported by claude.")
+	m.define("ralphkit_tests_runs_total", "counter", "Test runs, by result.")
+	m.define("ralphkit_tests_failed_total", "counter", "Test runs that failed.")
+	m.define("ralphkit_tests_duration_seconds", "histogram", "Wall time of test runs.")
+	m.define("ralphkit_notes_delivered_total", "counter", "Operator notes delivered to the agent.")
+	return m
+}
+
+func (m *metricSet) define(name, kind, help string) {
+	mt := &metric{name: name, help: help, kind: kind, samples: map[string]float64{}, histograms: map[string][]float64{}}
+	m.metrics = append(m.metrics, mt)
+	m.byName[name] = mt
+}
+
+func (m *metricSet) set(name string, v float64, labels ...string) {
+	m.byName[name].samples[renderLabels(labels)] = v
+}
+
+func (m *metricSet) inc(name string, labels ...string) {
+	m.byName[name].samples[renderLabels(labels)]++
+}
+
+func (m *metricSet) add(name string, v float64, labels ...string) {
+	m.byName[name].samples[renderLabels(labels)] += v
+}
+
+func (m *metricSet) observe(name string, v float64, labels ...string) {
+	key := renderLabels(labels)
+	m.byName[name].histograms[key] = append(m.byName[name].histograms[key], v)
+}
+
+func (m *metricSet) addSession(s *session.State, events []session.Event) {
+	base := []string{"session", s.Name, "model", s.Model}
+	status := s.Status
+	if status == "running" && s.Paused {
+		status = "paused"
+	}
+	for _, st := range sessionStatuses {
+		v := 0.0
+		if st == status {
+			v = 1
+		}
+		m.set("ralphkit_session_status", v, append(base, "status", st)...)
+	}
+	m.set("ralphkit_session_iterations", float64(s.Iterations), base...)
+	m.set("ralphkit_session_max_iterations", float64(s.MaxIterations), base...)
+	m.set("ralphkit_session_tasks_done", float64(s.TasksDone), base...)
+	m.set("ralphkit_session_tasks_total", float64(s.TasksTotal), base...)
+
+	// Make counters present from the first scrape so rate() sees the start.
+	m.set("ralphkit_iterations_total", 0, base...)
+	m.set("ralphkit_agent_runs_total", 0, base...)
+	for _, t := range tokenTypes {
+		m.set("ralphkit_tokens_total", 0, append(base, "type", t)...)
+	}
+	m.set("ralphkit_cost_usd_total", 0, base...)
+	m.set("ralphkit_tests_failed_total", 0, base...)
+	m.set("ralphkit_notes_delivered_total", 0, base...)
+
+	// An iteration lasts from its start event to the next iteration or the
+	// end of the loop; the running iteration is not observed yet.
+	var iterStart *session.Event
+	for i := range events {
+		e := &events[i]
+		switch e.Type {
+		case session.EventIteration:
+			if iterStart != nil {
+				m.observe("ralphkit_iteration_duration_seconds", e.Time.Sub(iterStart.Time).Seconds(), base...)
+			}
+			iterStart = e
+			m.inc("ralphkit_iterations_total", base...)
+		case session.EventEnd:
+			if iterStart != nil {
+				m.observe("ralphkit_iteration_duration_seconds", e.Time.Sub(iterStart.Time).Seconds(), base...)
+			}
+			iterStart = nil
+		case session.EventAgent:
+			m.inc("ralphkit_agent_runs_total", base...)
+			m.observe("ralphkit_agent_duration_seconds", e.Duration, base...)
+			if e.Status == "error" {
+				class := e.Class
+				if class == "" {
+					class = "other"
+				}
+				m.inc("ralphkit_agent_errors_total", append(base, "class", class)...)
+			}
+			if u := e.Usage; u != nil {
+				for t, n := range map[string]int{"input": u.InputTokens, "output": u.OutputTokens, "cache_read": u.CacheReadTokens, "cache_write": u.CacheWriteTokens} {
+					m.add("ralphkit_tokens_total", float64(n), append(base, "type", t)...)
+				}
+				m.add("ralphkit_cost_usd_total", u.CostUSD, base...)
+			}
+		case session.EventTests:
+			m.inc("ralphkit_tests_runs_total", append(base, "result", e.Status)...)
+			m.observe("ralphkit_tests_duration_seconds", e.Duration, base...)
+			if e.Status == "failed" {
+				m.inc("ralphkit_tests_failed_total", base...)
+			}
+		case session.EventNote:
+			m.inc("ralphkit_notes_delivered_total", base...)
+		}
+	}
+}
+
+func (m *metricSet) write(w io.Writer) {
+	for _, mt := range m.metrics {
+		if len(mt.samples) == 0 && len(mt.histograms) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", mt.name, mt.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", mt.name, mt.kind)
+		for _, labels := range sortedKeys(mt.samples) {
+			fmt.Fprintf(w, "%s%s %s\n", mt.name, wrapLabels(labels), formatFloat(mt.samples[labels]))
+		}
+		for _, labels := range sortedKeys(mt.histograms) {
+			writeHistogram(w, mt.name, labels, mt.histograms[labels])
+		}
+	}
+}
+
+func writeHistogram(w io.Writer, name, labels string, values []float64) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	for _, le := range durationBuckets {
+		count := 0
+		for _, v := range values {
+			if v <= le {
+				count++
+			}
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", name, labels, sep, formatFloat(le), count)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, len(values))
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, wrapLabels(labels), formatFloat(sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, wrapLabels(labels), len(values))
+}
+
+// renderLabels turns name/value pairs into `a="x",b="y"`.
+func renderLabels(pairs []string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", pairs[i], escapeLabel(pairs[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
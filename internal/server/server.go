@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bufio"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kfroemming/ralphkit/internal/session"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// pollInterval is how often log streams check for new output.
+const pollInterval = 500 * time.Millisecond
+
+// Handler serves the session API, Prometheus metrics and the web dashboard. If token is not
+// empty, every request must carry it as a bearer token or a token query
+// parameter. Without a token, only requests addressed to a loopback host
+// are served, so a web page can't reach the API through DNS rebinding.
+func Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", handleIndex)
+	mux.HandleFunc("GET /api/sessions", handleList)
+	mux.HandleFunc("GET /api/sessions/{name}", handleShow)
+	mux.HandleFunc("GET /api/sessions/{name}/events", handleEvents)
+	mux.HandleFunc("GET /api/sessions/{name}/log", handleLog)
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	if token == "" {
+		return requireLoopbackHost(mux)
+	}
+	return requireToken(token, mux)
+}
+
+func requireLoopbackHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !IsLoopback(strings.Trim(host, "[]")) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsLoopback reports whether host names this machine: localhost or a
+// loopback address.
+func IsLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.URL.Query().Get("token")
+		if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			got = auth
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func handleList(w http.ResponseWriter, r *http.Request) {
+	sessions, err := session.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]*session.State, len(sessions))
+	for i, s := range sessions {
+		out[i] = public(s)
+	}
+	writeJSON(w, out)
+}
+
+func handleShow(w http.ResponseWriter, r *http.Request) {
+	s, ok := loadSession(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, public(s))
+}
+
+// public returns a copy of a session without its liveness nonce, which only
+// the owning loop should know.
+func public(s *session.State) *session.State {
+	c := *s
+	c.Nonce = ""
+	return &c
+}
+
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	s, ok := loadSession(w, r)
+	if !ok {
+		return
+	}
+	events, err := session.Events(s)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if events == nil {
+		events = []session.Event{}
+	}
+	writeJSON(w, events)
+}
+
+// handleLog streams a session's log as server-sent events: one "line" event
+// per log line (existing output first), then an "end" event once the
+// session is no longer running.
+func handleLog(w http.ResponseWriter, r *http.Request) {
+	s, ok := loadSession(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	var reader *bufio.Reader
+	var partial string
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if f == nil && s.LogFile != "" {
+			if opened, err := os.Open(s.LogFile); err == nil {
+				f = opened
+				reader = bufio.NewReader(f)
+			}
+		}
+		if reader != nil {
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					partial += line
+					if err != io.EOF {
+						return
+					}
+					break
+				}
+				writeSSE(w, "line", strings.TrimSuffix(partial+line, "\n"))
+				partial = ""
+			}
+		}
+		flusher.Flush()
+
+		if ended(s.Name) {
+			if partial != "" {
+				writeSSE(w, "line", partial)
+			}
+			writeSSE(w, "end", "")
+			flusher.Flush()
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func ended(name string) bool {
+	s, err := session.Load(name)
+	return err != nil || s.Status != "running" || !session.Alive(s)
+}
+
+func loadSession(w http.ResponseWriter, r *http.Request) (*session.State, bool) {
+	s, err := session.Load(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return s, true
+}
+
+func writeSSE(w io.Writer, event, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
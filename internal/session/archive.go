@@ -0,0 +1,114 @@
+package session
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ArchiveDir returns the directory where archived runs are stored, next to
+// the active sessions directory, creating it if needed.
+func ArchiveDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(filepath.Dir(dir), "archive")
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// archive compresses a finished session's state, events, log and queued notes into
+// a tarball in ArchiveDir, stamped with the run's start time, then removes
+// the originals.
+func archive(dir string, s *State) error {
+	archiveDir, err := ArchiveDir()
+	if err != nil {
+		return err
+	}
+	base := fmt.Sprintf("%s-%s", s.Name, s.StartTime.Format("20060102-150405"))
+	dest := filepath.Join(archiveDir, base+".tar.gz")
+	for n := 2; fileExists(dest); n++ {
+		dest = filepath.Join(archiveDir, fmt.Sprintf("%s-%d.tar.gz", base, n))
+	}
+
+	tmp, err := os.CreateTemp(archiveDir, base+".tar.gz.tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	state, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := addTarFile(tw, s.Name+".json", state, finishedAt(s)); err != nil {
+		tmp.Close()
+		return err
+	}
+	files := []string{
+		filepath.Join(dir, s.Name+".inbox"),
+		filepath.Join(dir, s.Name+".events.jsonl"),
+	}
+	if s.LogFile != "" {
+		files = append(files, s.LogFile)
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := addTarFile(tw, filepath.Base(path), data, finishedAt(s)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return err
+	}
+	remove(dir, s)
+	return nil
+}
+
+func addTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
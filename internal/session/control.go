@@ -0,0 +1,67 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Pause asks a running session to wait before starting its next iteration.
+func Pause(name string) error {
+	s, err := Load(name)
+	if err != nil {
+		return err
+	}
+	if s.Status != "running" {
+		return fmt.Errorf("session %q is not running (status: %s)", name, s.Status)
+	}
+	return os.WriteFile(filepath.Join(s.Dir, name+".paused"), nil, 0o644)
+}
+
+// Resume lets a paused session continue.
+func Resume(name string) error {
+	s, err := Load(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(s.Dir, name+".paused"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("session %q is not paused", name)
+	}
+	return err
+}
+
+// PauseRequested reports whether a session in the active sessions directory
+// has been asked to pause.
+func PauseRequested(name string) bool {
+	dir, err := Dir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(dir, name+".paused"))
+	return err == nil
+}
+
+// RequestStop asks a running session to stop once its current iteration
+// finishes, rather than interrupting it like Stop.
+func RequestStop(name string) error {
+	s, err := Load(name)
+	if err != nil {
+		return err
+	}
+	if s.Status != "running" {
+		return fmt.Errorf("session %q is not running (status: %s)", name, s.Status)
+	}
+	return os.WriteFile(filepath.Join(s.Dir, name+".stop"), nil, 0o644)
+}
+
+// StopRequested reports whether a session in the active sessions directory
+// has been asked to stop after its current iteration.
+func StopRequested(name string) bool {
+	dir, err := Dir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(dir, name+".stop"))
+	return err == nil
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Event types recorded in a session's event log.
+const (
+	EventStart     = "start"     // the loop started; Snapshot is the initial worktree
+	EventIteration = "iteration" // an iteration started
+	EventAgent     = "agent"     // the agent finished; Status is ok or error, with Class set on errors, Usage what it consumed, and Snapshot is the worktree afterwards
+	EventTests     = "tests"     // tests finished; Status is passed or failed
+	EventNote      = "note"      // an operator note was delivered
+	EventTask      = "task"      // a task finished in per-task mode; Task is its ID and Status its outcome
+	EventEnd       = "end"       // the loop ended; Status is the final status
+)
+
+// Event is one entry in a session's event log.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Iteration int       `json:"iteration,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Class     string    `json:"class,omitempty"`
+	Duration  float64   `json:"durationSeconds,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	// Task is the ID of the PRD task being worked on in per-task mode.
+	Task string `json:"task,omitempty"`
+	// Snapshot is a git tree hash of the working directory, if it is in a
+	// git repository.
+	Snapshot string `json:"snapshot,omitempty"`
+	// Usage is what an agent invocation consumed, if claude reported it.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Usage is the tokens and spend of one agent invocation, as reported by
+// claude.
+type Usage struct {
+	InputTokens      int     `json:"inputTokens"`
+	OutputTokens     int     `json:"outputTokens"`
+	CacheReadTokens  int     `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens int     `json:"cacheWriteTokens,omitempty"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// RecordEvent appends an event to the log of a session in the active
+// sessions directory, stamping it with the current time if unset.
+func RecordEvent(name string, e Event) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name+".events.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Events returns a session's event log, oldest first.
+func Events(s *State) ([]Event, error) {
+	f, err := os.Open(filepath.Join(s.Dir, s.Name+".events.jsonl"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// HeartbeatInterval is how often a running loop refreshes its heartbeat.
+	HeartbeatInterval = 15 * time.Second
+	// heartbeatTimeout is how long a heartbeat may go stale before the
+	// session is considered dead.
+	heartbeatTimeout = 4 * HeartbeatInterval
+	// startTimeSlack absorbs rounding between how the start time was
+	// recorded and how it is read back.
+	startTimeSlack = 2 * time.Second
+)
+
+// NewNonce returns a random identifier for a single run of a session.
+func NewNonce() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Identify fills in the liveness fields for the current process.
+func (s *State) Identify() {
+	s.PID = os.Getpid()
+	s.Nonce = NewNonce()
+	if t, err := processStartTime(s.PID); err == nil {
+		s.ProcessStart = &t
+	}
+	now := time.Now()
+	s.Heartbeat = &now
+}
+
+// Alive reports whether the process that owns a session is still running.
+// The PID must exist, its start time must match the recorded one (guarding
+// against PID reuse after a reboot), and the heartbeat must be fresh.
+func Alive(s *State) bool {
+	if !processAlive(s.PID) {
+		return false
+	}
+	if s.ProcessStart != nil {
+		if t, err := processStartTime(s.PID); err == nil {
+			if d := t.Sub(*s.ProcessStart); d > startTimeSlack || d < -startTimeSlack {
+				return false
+			}
+		}
+	}
+	if s.Heartbeat != nil && time.Since(*s.Heartbeat) > heartbeatTimeout {
+		return false
+	}
+	return true
+}
+
+// Beat refreshes the heartbeat of a running session. It returns an error if
+// the session on disk now belongs to a different run.
+func Beat(name, nonce string) error {
+	return withLock(func(dir string) error {
+		s, err := load(dir, name)
+		if err != nil {
+			return err
+		}
+		if s.Nonce != nonce {
+			return fmt.Errorf("session %q has been taken over by another run", name)
+		}
+		now := time.Now()
+		s.Heartbeat = &now
+		return save(dir, s)
+	})
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Log sections, as written into session logs by the loop.
+const (
+	SectionAgent = "agent"
+	SectionTests = "tests"
+)
+
+// markerPrefix starts every section marker line in a session log.
+const markerPrefix = "#### ralphkit: "
+
+// Marker returns the log line that opens a section of an iteration.
+func Marker(section string, iteration int) string {
+	return fmt.Sprintf("%s%s %d", markerPrefix, section, iteration)
+}
+
+// ParseMarker reports whether line is a section marker and, if so, which
+// section and iteration it opens.
+func ParseMarker(line string) (section string, iteration int, ok bool) {
+	rest, ok := strings.CutPrefix(line, markerPrefix)
+	if !ok {
+		return "", 0, false
+	}
+	section, num, ok := strings.Cut(rest, " ")
+	if !ok || (section != SectionAgent && section != SectionTests) {
+		return "", 0, false
+	}
+	iteration, err := strconv.Atoi(strings.TrimSpace(num))
+	if err != nil {
+		return "", 0, false
+	}
+	return section, iteration, true
+}
@@ -2,19 +2,26 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"syscall"
 	"time"
+
+	"github.com/kfroemming/ralphkit/internal/paths"
 )
 
 // State represents a saved session.
 type State struct {
 	Name          string     `json:"name"`
-	Status        string     `json:"status"` // running, stopped, complete
+	Status        string     `json:"status"` // see the Status constants
 	PID           int        `json:"pid"`
+	ProcessStart  *time.Time `json:"processStart,omitempty"`
+	Nonce         string     `json:"nonce,omitempty"`
+	Heartbeat     *time.Time `json:"heartbeat,omitempty"`
 	Iterations    int        `json:"iterations"`
 	MaxIterations int        `json:"maxIterations"`
 	Model         string     `json:"model"`
@@ -23,80 +30,236 @@ type State struct {
 	StartTime     time.Time  `json:"startTime"`
 	EndTime       *time.Time `json:"endTime"`
 	LogFile       string     `json:"logFile"`
+	Notes         []Note     `json:"notes,omitempty"`
+	Paused        bool       `json:"paused,omitempty"`
+	TasksDone     int        `json:"tasksDone,omitempty"`
+	TasksTotal    int        `json:"tasksTotal,omitempty"`
+	LastTests     string     `json:"lastTests,omitempty"` // passed, failed
+	// Reason says why a finished session ended; see the Reason constants.
+	Reason string `json:"reason,omitempty"`
+	// Tasks records each PRD task worked on in per-task mode.
+	Tasks []*TaskRun `json:"tasks,omitempty"`
+
+	// Dir is the sessions directory the state was loaded from.
+	Dir string `json:"-"`
+}
+
+// Statuses a session can be in, recorded in State.Status.
+const (
+	StatusRunning  = "running"
+	StatusComplete = "complete"
+	StatusStopped  = "stopped"
+	StatusCrashed  = "crashed"
+	// StatusPaused is never recorded; it is how a running session that is
+	// paused is shown and filtered.
+	StatusPaused = "paused"
+)
+
+// Statuses lists every status a session can be shown with.
+var Statuses = []string{StatusRunning, StatusPaused, StatusComplete, StatusStopped, StatusCrashed}
+
+// DisplayStatus returns the session's status, or StatusPaused for a running
+// session that is paused.
+func (s *State) DisplayStatus() string {
+	if s.Status == StatusRunning && s.Paused {
+		return StatusPaused
+	}
+	return s.Status
 }
 
+// Reasons a session ended, recorded in State.Reason.
+const (
+	ReasonComplete      = "complete"        // the agent finished the PRD
+	ReasonMaxIterations = "max_iterations"  // the iteration limit was reached
+	ReasonBudget        = "budget_exceeded" // the time budget ran out
+	ReasonStalled       = "stalled"         // no PRD progress for too long
+	ReasonInterrupted   = "interrupted"     // the loop was interrupted or stopped
+	ReasonStopRequested = "stop_requested"  // stopped after an iteration on request
+	ReasonAgentError    = "agent_error"     // the agent could not be run
+	ReasonHookFailed    = "hook_failed"     // a hook with the abort policy failed
+	ReasonTasksFailed   = "tasks_failed"    // every task left used up its attempts
+)
+
+var (
+	// StoreDir, when set, overrides the directory new sessions are stored in.
+	StoreDir string
+	// ExtraDirs are further sessions directories that List and Load search,
+	// e.g. the global store when sessions are kept per project.
+	ExtraDirs []string
+)
+
 // Dir returns the sessions directory, creating it if needed.
 func Dir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+	dir := StoreDir
+	if dir == "" {
+		home, err := paths.Home()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, "sessions")
 	}
-	dir := filepath.Join(home, ".ralphkit", "sessions")
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", err
 	}
 	return dir, nil
 }
 
-// Save persists a session state to disk.
+// Dirs returns the active sessions directory followed by any existing
+// ExtraDirs, without duplicates.
+func Dirs() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	all := []string{dir}
+	for _, d := range ExtraDirs {
+		if d == "" || slices.Contains(all, d) {
+			continue
+		}
+		if info, err := os.Stat(d); err == nil && info.IsDir() {
+			all = append(all, d)
+		}
+	}
+	return all, nil
+}
+
+// Save persists a session state to disk. Saving a running session also
+// refreshes its heartbeat, since only the owning loop saves it.
 func Save(s *State) error {
+	if s.Status == "running" {
+		now := time.Now()
+		s.Heartbeat = &now
+	}
+	return withLock(func(dir string) error {
+		return save(dir, s)
+	})
+}
+
+func save(dir string, s *State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, s.Name+".json"), data)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and
+// renames it over path, so readers never observe a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// withLock runs fn while holding an exclusive advisory lock on the sessions
+// directory. Every read-modify-write of session files goes through it so
+// concurrent ralphkit processes don't clobber each other's state.
+func withLock(fn func(dir string) error) error {
 	dir, err := Dir()
 	if err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(s, "", "  ")
+	return withLockIn(dir, fn)
+}
+
+// withLockIn is withLock for an explicit sessions directory.
+func withLockIn(dir string, fn func(dir string) error) error {
+	f, err := os.OpenFile(filepath.Join(dir, ".lock"), os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(dir, s.Name+".json"), data, 0o644)
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock sessions directory: %w", err)
+	}
+	defer unlockFile(f)
+	return fn(dir)
 }
 
-// Load reads a session state from disk.
+// Load reads a session state from disk, searching the active sessions
+// directory first and then ExtraDirs.
 func Load(name string) (*State, error) {
-	dir, err := Dir()
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	all, err := Dirs()
 	if err != nil {
 		return nil, err
 	}
+	for _, dir := range all {
+		s, err := load(dir, name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return s, err
+	}
+	return nil, fmt.Errorf("session %q not found", name)
+}
+
+func load(dir, name string) (*State, error) {
 	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
 	if err != nil {
-		return nil, fmt.Errorf("session %q not found", name)
+		return nil, fmt.Errorf("session %q not found: %w", name, err)
 	}
 	var s State
 	if err := json.Unmarshal(data, &s); err != nil {
 		return nil, err
 	}
+	s.Dir = dir
 	return &s, nil
 }
 
-// List returns all saved sessions.
+// List returns all saved sessions across the active sessions directory and
+// ExtraDirs.
 func List() ([]*State, error) {
-	dir, err := Dir()
-	if err != nil {
-		return nil, err
-	}
-	entries, err := os.ReadDir(dir)
+	all, err := Dirs()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
 		return nil, err
 	}
 	var sessions []*State
-	for _, e := range entries {
-		if filepath.Ext(e.Name()) != ".json" {
-			continue
-		}
-		name := e.Name()[:len(e.Name())-5]
-		s, err := Load(name)
+	for _, dir := range all {
+		entries, err := os.ReadDir(dir)
 		if err != nil {
-			continue
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
 		}
-		// Check if "running" sessions are actually still alive.
-		if s.Status == "running" && !processAlive(s.PID) {
-			s.Status = "stopped"
-			_ = Save(s)
+		for _, e := range entries {
+			if filepath.Ext(e.Name()) != ".json" {
+				continue
+			}
+			name := e.Name()[:len(e.Name())-5]
+			s, err := load(dir, name)
+			if err != nil {
+				continue
+			}
+			// Check if "running" sessions are actually still alive.
+			if s.Status == "running" && !Alive(s) {
+				s = markDead(dir, name)
+				if s == nil {
+					continue
+				}
+			}
+			sessions = append(sessions, s)
 		}
-		sessions = append(sessions, s)
 	}
 	sort.Slice(sessions, func(i, j int) bool {
 		return sessions[i].StartTime.After(sessions[j].StartTime)
@@ -104,56 +267,147 @@ func List() ([]*State, error) {
 	return sessions, nil
 }
 
+// markDead re-reads a session under the lock and marks it crashed if its
+// process is gone. It returns the current state, or nil if it vanished.
+func markDead(dir, name string) *State {
+	var s *State
+	_ = withLockIn(dir, func(dir string) error {
+		var err error
+		s, err = load(dir, name)
+		if err != nil {
+			s = nil
+			return err
+		}
+		if s.Status != "running" || Alive(s) {
+			return nil
+		}
+		s.Status = "crashed"
+		return save(dir, s)
+	})
+	return s
+}
+
 // Stop sends SIGINT to a running session's process.
 func Stop(name string) error {
-	s, err := Load(name)
+	found, err := Load(name)
 	if err != nil {
 		return err
 	}
-	if s.Status != "running" {
-		return fmt.Errorf("session %q is not running (status: %s)", name, s.Status)
-	}
-	if s.PID > 0 {
+	return withLockIn(found.Dir, func(dir string) error {
+		s, err := load(dir, name)
+		if err != nil {
+			return err
+		}
+		if s.Status != "running" {
+			return fmt.Errorf("session %q is not running (status: %s)", name, s.Status)
+		}
+		if !Alive(s) {
+			// Never signal a PID we can't prove still belongs to this session.
+			s.Status = "crashed"
+			if err := save(dir, s); err != nil {
+				return err
+			}
+			return fmt.Errorf("session %q is no longer running (marked crashed)", name)
+		}
 		proc, err := os.FindProcess(s.PID)
 		if err == nil {
 			_ = proc.Signal(syscall.SIGINT)
 		}
-	}
-	now := time.Now()
-	s.Status = "stopped"
-	s.EndTime = &now
-	return Save(s)
+		now := time.Now()
+		s.Status = "stopped"
+		s.EndTime = &now
+		return save(dir, s)
+	})
 }
 
-// Clean removes all completed or stopped session files.
-func Clean() (int, error) {
-	dir, err := Dir()
-	if err != nil {
-		return 0, err
-	}
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return 0, err
+// CleanOptions selects which finished sessions Clean removes.
+type CleanOptions struct {
+	// OlderThan only matches sessions that ended (or started, if they never
+	// recorded an end) at least this long ago. Zero matches any age.
+	OlderThan time.Duration
+	// Statuses restricts matches to these statuses. Empty means complete,
+	// stopped and crashed. Running sessions are never cleaned.
+	Statuses []string
+	// KeepLast spares the N most recent matching sessions.
+	KeepLast int
+	// Archive compresses each session into ArchiveDir before removing it.
+	Archive bool
+	// DryRun reports what would be cleaned without touching anything.
+	DryRun bool
+}
+
+// Clean removes finished sessions matching opts, along with their logs, and
+// returns the sessions it removed (or would remove, for a dry run).
+func Clean(opts CleanOptions) ([]*State, error) {
+	statuses := opts.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{StatusComplete, StatusStopped, StatusCrashed}
 	}
-	count := 0
-	for _, e := range entries {
-		if filepath.Ext(e.Name()) != ".json" {
-			continue
-		}
-		name := e.Name()[:len(e.Name())-5]
-		s, err := Load(name)
+	var cleaned []*State
+	err := withLock(func(dir string) error {
+		entries, err := os.ReadDir(dir)
 		if err != nil {
-			continue
+			return err
+		}
+		var matches []*State
+		for _, e := range entries {
+			if filepath.Ext(e.Name()) != ".json" {
+				continue
+			}
+			s, err := load(dir, e.Name()[:len(e.Name())-5])
+			if err != nil {
+				continue
+			}
+			if s.Status == "running" || !slices.Contains(statuses, s.Status) {
+				continue
+			}
+			if opts.OlderThan > 0 && time.Since(finishedAt(s)) < opts.OlderThan {
+				continue
+			}
+			matches = append(matches, s)
 		}
-		if s.Status == "complete" || s.Status == "stopped" {
-			os.Remove(filepath.Join(dir, e.Name()))
-			if s.LogFile != "" {
-				os.Remove(s.LogFile)
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].StartTime.After(matches[j].StartTime)
+		})
+		if opts.KeepLast > 0 {
+			matches = matches[min(opts.KeepLast, len(matches)):]
+		}
+		for _, s := range matches {
+			if !opts.DryRun {
+				if opts.Archive {
+					if err := archive(dir, s); err != nil {
+						return fmt.Errorf("failed to archive session %q: %w", s.Name, err)
+					}
+				} else {
+					remove(dir, s)
+				}
 			}
-			count++
+			cleaned = append(cleaned, s)
 		}
+		return nil
+	})
+	return cleaned, err
+}
+
+// finishedAt returns when a session ended, falling back to its start time.
+func finishedAt(s *State) time.Time {
+	if s.EndTime != nil {
+		return *s.EndTime
+	}
+	return s.StartTime
+}
+
+// remove deletes a session's state, log and control files.
+func remove(dir string, s *State) {
+	os.Remove(filepath.Join(dir, s.Name+".json"))
+	if s.LogFile != "" {
+		os.Remove(s.LogFile)
 	}
-	return count, nil
+	os.Remove(filepath.Join(dir, s.Name+".inbox"))
+	removeTaken(dir, s.Name)
+	os.Remove(filepath.Join(dir, s.Name+".paused"))
+	os.Remove(filepath.Join(dir, s.Name+".stop"))
+	os.Remove(filepath.Join(dir, s.Name+".events.jsonl"))
 }
 
 // LogPath returns the log file path for a session.
@@ -164,15 +418,3 @@ func LogPath(name string) (string, error) {
 	}
 	return filepath.Join(dir, name+".log"), nil
 }
-
-func processAlive(pid int) bool {
-	if pid <= 0 {
-		return false
-	}
-	proc, err := os.FindProcess(pid)
-	if err != nil {
-		return false
-	}
-	err = proc.Signal(syscall.Signal(0))
-	return err == nil
-}
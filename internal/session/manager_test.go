@@ -0,0 +1,192 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useStore points the package at a fresh sessions directory for one test.
+func useStore(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	oldStore, oldExtra := StoreDir, ExtraDirs
+	StoreDir, ExtraDirs = dir, nil
+	t.Cleanup(func() { StoreDir, ExtraDirs = oldStore, oldExtra })
+	return dir
+}
+
+// checkStore fails if any session file is unreadable or a temp file was
+// left behind.
+func checkStore(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temp file left behind: %s", e.Name())
+		}
+		if filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s State
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Errorf("%s is corrupt: %v", e.Name(), err)
+		}
+	}
+}
+
+func TestConcurrentSaveListStopClean(t *testing.T) {
+	dir := useStore(t)
+	const writers, rounds = 4, 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("writer-%d", w)
+			for i := 0; i < rounds; i++ {
+				// A running session with no live process gets marked
+				// crashed by List and Stop while this goroutine keeps
+				// saving it.
+				s := &State{Name: name, Status: "running", Iterations: i, StartTime: time.Now()}
+				if i%3 == 0 {
+					s.Status = "complete"
+				}
+				if err := Save(s); err != nil {
+					errs <- fmt.Errorf("Save: %w", err)
+					return
+				}
+				_ = Stop(name) // not running or not found are both fine
+			}
+		}()
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				sessions, err := List()
+				if err != nil {
+					errs <- fmt.Errorf("List: %w", err)
+					return
+				}
+				for _, s := range sessions {
+					if s.Name == "" {
+						errs <- fmt.Errorf("List returned a session without a name")
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds/2; i++ {
+			if _, err := Clean(CleanOptions{}); err != nil {
+				errs <- fmt.Errorf("Clean: %w", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	checkStore(t, dir)
+}
+
+// TestHelperIncrement is run in subprocesses by TestLockAcrossProcesses.
+func TestHelperIncrement(t *testing.T) {
+	dir := os.Getenv("RALPHKIT_TEST_SESSIONS")
+	if dir == "" {
+		t.Skip("helper process only")
+	}
+	StoreDir, ExtraDirs = dir, nil
+	for i := 0; i < 25; i++ {
+		err := withLock(func(dir string) error {
+			s, err := load(dir, "counter")
+			if err != nil {
+				return err
+			}
+			s.Iterations++
+			return save(dir, s)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLockAcrossProcesses(t *testing.T) {
+	dir := useStore(t)
+	if err := Save(&State{Name: "counter", Status: "complete", StartTime: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	const procs = 4
+	var cmds []*exec.Cmd
+	for i := 0; i < procs; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperIncrement$")
+		cmd.Env = append(os.Environ(), "RALPHKIT_TEST_SESSIONS="+dir)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	// Readers never take the lock, so they must never see a partial write.
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "counter.json"))
+			if err != nil {
+				t.Errorf("reading counter: %v", err)
+				return
+			}
+			var s State
+			if err := json.Unmarshal(data, &s); err != nil {
+				t.Errorf("counter.json is corrupt mid-run: %v", err)
+				return
+			}
+		}
+	}()
+
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("helper failed: %v", err)
+		}
+	}
+	close(stop)
+	<-done
+	s, err := Load("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Iterations != procs*25 {
+		t.Errorf("counter = %d, want %d; increments were lost", s.Iterations, procs*25)
+	}
+	checkStore(t, dir)
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const maxNameLen = 64
+
+var (
+	validName  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	unsafeRune = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// ValidateName checks that a session name is safe to use as a file name.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("session name is empty")
+	}
+	if len(name) > maxNameLen {
+		return fmt.Errorf("session name %q is longer than %d characters", name, maxNameLen)
+	}
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid session name %q: use letters, digits, '.', '_' and '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// SanitizeName turns arbitrary text (such as a PRD file name) into a valid
+// session name.
+func SanitizeName(s string) string {
+	s = unsafeRune.ReplaceAllString(s, "-")
+	s = strings.TrimLeft(s, ".-_")
+	if len(s) > maxNameLen {
+		s = s[:maxNameLen]
+	}
+	if s == "" {
+		s = "session"
+	}
+	return s
+}
+
+// Create registers a new running session. It fails if s.Name belongs to a
+// session that is still running. If the name belongs to a finished session,
+// the new run gets a numeric suffix, or with force the previous run's state
+// and log are archived and the name is reused. s.Name and s.LogFile are
+// updated to the name actually used.
+func Create(s *State, force bool) error {
+	if err := ValidateName(s.Name); err != nil {
+		return err
+	}
+	return withLock(func(dir string) error {
+		if prev, err := load(dir, s.Name); err == nil {
+			if prev.Status == "running" && Alive(prev) {
+				return fmt.Errorf("session %q is already running (pid %d)", s.Name, prev.PID)
+			}
+			if force {
+				if err := archive(dir, prev); err != nil {
+					return fmt.Errorf("failed to archive previous run of %q: %w", s.Name, err)
+				}
+			} else {
+				s.Name = nextFreeName(dir, s.Name)
+			}
+		}
+		s.LogFile = filepath.Join(dir, s.Name+".log")
+		os.Remove(filepath.Join(dir, s.Name+".inbox"))
+		removeTaken(dir, s.Name)
+		os.Remove(filepath.Join(dir, s.Name+".paused"))
+		os.Remove(filepath.Join(dir, s.Name+".stop"))
+		os.Remove(filepath.Join(dir, s.Name+".events.jsonl"))
+		return save(dir, s)
+	})
+}
+
+// nextFreeName returns the first of name-2, name-3, ... with no saved state.
+func nextFreeName(dir, name string) string {
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		base := name
+		if len(base)+len(suffix) > maxNameLen {
+			base = base[:maxNameLen-len(suffix)]
+		}
+		candidate := base + suffix
+		if _, err := os.Stat(filepath.Join(dir, candidate+".json")); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Note is an operator message queued for a running session.
+type Note struct {
+	Message     string     `json:"message"`
+	QueuedAt    time.Time  `json:"queuedAt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	Iteration   int        `json:"iteration,omitempty"`
+}
+
+// InboxPath returns the path of the queued-notes file for a session, in
+// whichever store holds the session.
+func InboxPath(name string) (string, error) {
+	s, err := Load(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, name+".inbox"), nil
+}
+
+// Say queues an operator note for a running session. The loop picks it up
+// at the start of its next iteration.
+func Say(name, message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return fmt.Errorf("message is empty")
+	}
+	s, err := Load(name)
+	if err != nil {
+		return err
+	}
+	if s.Status != "running" {
+		return fmt.Errorf("session %q is not running (status: %s)", name, s.Status)
+	}
+	path := filepath.Join(s.Dir, name+".inbox")
+	data, err := json.Marshal(Note{Message: message, QueuedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// TakeNotes removes and returns all notes queued for a session, oldest first.
+func TakeNotes(name string) ([]Note, error) {
+	path, err := InboxPath(name)
+	if err != nil {
+		return nil, err
+	}
+	// Move the inbox aside first so notes queued while we read land in a
+	// fresh file instead of being lost. Each take gets its own name, and
+	// batches left behind by an earlier take that died before reading them
+	// are picked up too.
+	taken := fmt.Sprintf("%s.taken.%d", path, time.Now().UnixNano())
+	if err := os.Rename(path, taken); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	batches, err := filepath.Glob(path + ".taken*")
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	for _, batch := range batches {
+		n, err := readNotes(batch)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n...)
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].QueuedAt.Before(notes[j].QueuedAt) })
+	for _, batch := range batches {
+		os.Remove(batch)
+	}
+	return notes, nil
+}
+
+// readNotes reads a file of queued notes, skipping lines that don't parse.
+func readNotes(path string) ([]Note, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var notes []Note
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var n Note
+		if err := json.Unmarshal(scanner.Bytes(), &n); err != nil || n.Message == "" {
+			continue
+		}
+		notes = append(notes, n)
+	}
+	return notes, scanner.Err()
+}
+
+// removeTaken deletes batches of notes that a take left unread.
+func removeTaken(dir, name string) {
+	batches, _ := filepath.Glob(filepath.Join(dir, name+".inbox.taken*"))
+	for _, batch := range batches {
+		os.Remove(batch)
+	}
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNotesInExtraStore(t *testing.T) {
+	active := useStore(t)
+	extra := t.TempDir()
+	StoreDir = extra
+	if err := Create(&State{Name: "loop", Status: "running", StartTime: time.Now()}, false); err != nil {
+		t.Fatal(err)
+	}
+	StoreDir, ExtraDirs = active, []string{extra}
+
+	if err := Say("loop", "use sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := TakeNotes("loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Message != "use sqlite" {
+		t.Errorf("notes = %+v", notes)
+	}
+}
+
+func TestTakeNotesPicksUpLeftovers(t *testing.T) {
+	dir := useStore(t)
+	if err := Create(&State{Name: "loop", Status: "running", StartTime: time.Now()}, false); err != nil {
+		t.Fatal(err)
+	}
+	// A take that died between moving the inbox aside and reading it.
+	leftover := `{"message":"first","queuedAt":"2026-01-01T00:00:00Z"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "loop.inbox.taken"), []byte(leftover), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Say("loop", "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	notes, err := TakeNotes("loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].Message != "first" || notes[1].Message != "second" {
+		t.Errorf("notes = %+v", notes)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "loop.inbox*")); len(left) != 0 {
+		t.Errorf("files left behind: %v", left)
+	}
+	if notes, _ := TakeNotes("loop"); len(notes) != 0 {
+		t.Errorf("notes delivered twice: %+v", notes)
+	}
+}
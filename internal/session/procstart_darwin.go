@@ -0,0 +1,19 @@
+//go:build darwin
+
+package session
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processStartTime asks ps for a process's start time.
+func processStartTime(pid int) (time.Time, error) {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("Mon Jan _2 15:04:05 2006", strings.TrimSpace(string(out)), time.Local)
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// processStartTime is not supported on this platform; liveness falls back
+// to the PID and heartbeat checks.
+func processStartTime(pid int) (time.Time, error) {
+	return time.Time{}, errors.ErrUnsupported
+}
@@ -0,0 +1,32 @@
+package session
+
+import "time"
+
+// Task outcomes, recorded in TaskRun.Status.
+const (
+	TaskWorking    = "working"    // the loop is on this task
+	TaskDone       = "done"       // the agent finished it and tests passed
+	TaskUnfinished = "unfinished" // the loop ended while on this task
+	TaskFailed     = "failed"     // the task used up its attempts and was skipped
+)
+
+// TaskRun records how one PRD task went in per-task mode.
+type TaskRun struct {
+	ID         string     `json:"id"`
+	Text       string     `json:"text"`
+	Status     string     `json:"status"`
+	Iterations int        `json:"iterations"`
+	LastTests  string     `json:"lastTests,omitempty"`
+	StartTime  time.Time  `json:"startTime"`
+	EndTime    *time.Time `json:"endTime,omitempty"`
+}
+
+// Task returns the run of the task with the given ID, or nil.
+func (s *State) Task(id string) *TaskRun {
+	for _, t := range s.Tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
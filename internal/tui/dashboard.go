@@ -0,0 +1,391 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/ui"
+)
+
+// refreshInterval is how often the dashboard reloads sessions and logs.
+const refreshInterval = time.Second
+
+// maxTableRows caps the session table so the log pane keeps some room.
+const maxTableRows = 10
+
+// RunDashboard shows a full-screen view of all sessions until the user quits.
+func RunDashboard() error {
+	_, err := tea.NewProgram(newDashboard(), tea.WithAltScreen()).Run()
+	return err
+}
+
+type tickMsg time.Time
+
+type shellDoneMsg struct{ err error }
+
+type dashboard struct {
+	sessions []*session.State
+	cursor   int
+	selected string
+
+	log   viewport.Model
+	tail  logReader
+	input textinput.Model
+	// saying is true while the operator types a note for the agent.
+	saying bool
+	flash  string
+
+	width, height int
+}
+
+func newDashboard() *dashboard {
+	in := textinput.New()
+	in.Placeholder = "guidance for the agent's next iteration"
+	in.Prompt = "say> "
+	d := &dashboard{log: viewport.New(80, 10), input: in}
+	d.refresh()
+	return d
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (d *dashboard) Init() tea.Cmd {
+	return tick()
+}
+
+func (d *dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		d.width, d.height = msg.Width, msg.Height
+		d.layout()
+		return d, nil
+
+	case tickMsg:
+		d.refresh()
+		return d, tick()
+
+	case shellDoneMsg:
+		if msg.err != nil {
+			d.flash = fmt.Sprintf("shell exited: %v", msg.err)
+		}
+		return d, nil
+
+	case tea.KeyMsg:
+		if d.saying {
+			return d.updateSay(msg)
+		}
+		return d.updateKeys(msg)
+	}
+
+	var cmd tea.Cmd
+	d.log, cmd = d.log.Update(msg)
+	return d, cmd
+}
+
+func (d *dashboard) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := d.current()
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return d, tea.Quit
+	case "up", "k":
+		d.move(-1)
+	case "down", "j":
+		d.move(1)
+	case "p":
+		if s != nil {
+			d.flash = togglePause(s)
+		}
+	case "s":
+		if s != nil {
+			if err := session.Stop(s.Name); err != nil {
+				d.flash = err.Error()
+			} else {
+				d.flash = fmt.Sprintf("Stopping %s.", s.Name)
+			}
+			d.refresh()
+		}
+	case "m":
+		if s != nil {
+			d.saying = true
+			d.input.Reset()
+			d.input.Focus()
+			d.layout()
+			return d, textinput.Blink
+		}
+	case "o":
+		if s != nil && s.WorkDir != "" {
+			shell := os.Getenv("SHELL")
+			if shell == "" {
+				shell = "sh"
+			}
+			c := exec.Command(shell)
+			c.Dir = s.WorkDir
+			return d, tea.ExecProcess(c, func(err error) tea.Msg { return shellDoneMsg{err} })
+		}
+	default:
+		var cmd tea.Cmd
+		d.log, cmd = d.log.Update(msg)
+		return d, cmd
+	}
+	return d, nil
+}
+
+func (d *dashboard) updateSay(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		d.saying = false
+		d.input.Blur()
+		d.layout()
+		return d, nil
+	case "enter":
+		d.saying = false
+		d.input.Blur()
+		d.layout()
+		if s := d.current(); s != nil {
+			if err := session.Say(s.Name, d.input.Value()); err != nil {
+				d.flash = err.Error()
+			} else {
+				d.flash = fmt.Sprintf("Queued note for %s.", s.Name)
+			}
+		}
+		return d, nil
+	}
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	return d, cmd
+}
+
+// togglePause resumes a session with a pending pause, or pauses it.
+func togglePause(s *session.State) string {
+	if err := session.Resume(s.Name); err == nil {
+		return fmt.Sprintf("Resumed %s.", s.Name)
+	}
+	if err := session.Pause(s.Name); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s will pause before its next iteration.", s.Name)
+}
+
+func (d *dashboard) current() *session.State {
+	if d.cursor < 0 || d.cursor >= len(d.sessions) {
+		return nil
+	}
+	return d.sessions[d.cursor]
+}
+
+func (d *dashboard) move(delta int) {
+	if len(d.sessions) == 0 {
+		return
+	}
+	d.cursor = min(max(d.cursor+delta, 0), len(d.sessions)-1)
+	d.selected = d.sessions[d.cursor].Name
+	d.refreshLog()
+}
+
+// refresh reloads the session list, keeping the selection on the same
+// session, and pulls new log output for it.
+func (d *dashboard) refresh() {
+	sessions, err := session.List()
+	if err != nil {
+		d.flash = err.Error()
+		return
+	}
+	d.sessions = sessions
+	d.cursor = 0
+	for i, s := range sessions {
+		if s.Name == d.selected {
+			d.cursor = i
+		}
+	}
+	if s := d.current(); s != nil {
+		d.selected = s.Name
+	}
+	d.refreshLog()
+	d.layout()
+}
+
+func (d *dashboard) refreshLog() {
+	s := d.current()
+	if s == nil {
+		d.log.SetContent("")
+		return
+	}
+	atBottom := d.log.AtBottom()
+	if d.tail.path != s.LogFile {
+		d.tail = logReader{path: s.LogFile}
+		atBottom = true
+	}
+	if d.tail.read() || atBottom {
+		d.log.SetContent(d.tail.text(nil))
+		if atBottom {
+			d.log.GotoBottom()
+		}
+	}
+}
+
+func (d *dashboard) tableHeight() int {
+	return min(max(len(d.sessions), 1), maxTableRows) + 1
+}
+
+func (d *dashboard) layout() {
+	if d.width == 0 {
+		return
+	}
+	// Title, table, log title, footer and the optional say prompt.
+	chrome := 3 + d.tableHeight()
+	if d.saying {
+		chrome++
+	}
+	d.log.Width = d.width
+	d.log.Height = max(d.height-chrome, 3)
+	d.input.Width = d.width - len(d.input.Prompt) - 1
+}
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	columnStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("8"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	helpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	flashStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	passStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	failStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// columns holds the widths of the session table columns; the last column
+// (work dir) takes the remaining space.
+var columns = []int{24, 9, 7, 10, 14, 7}
+
+func (d *dashboard) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf("ralphkit dashboard — %d session(s)", len(d.sessions))))
+	b.WriteString("\n")
+	b.WriteString(columnStyle.Render(formatRow([]string{"SESSION", "STATUS", "ITER", "ELAPSED", "PROGRESS", "TESTS", "WORKDIR"}, nil)))
+	b.WriteString("\n")
+
+	if len(d.sessions) == 0 {
+		b.WriteString(helpStyle.Render("No sessions found."))
+		b.WriteString("\n")
+	}
+	// Scroll the table so the cursor stays visible.
+	start := max(d.cursor-maxTableRows+1, 0)
+	end := min(start+maxTableRows, len(d.sessions))
+	for i := start; i < end; i++ {
+		row := d.row(d.sessions[i], i == d.cursor)
+		b.WriteString(row)
+		b.WriteString("\n")
+	}
+
+	label := "log"
+	if s := d.current(); s != nil {
+		label = "log: " + s.Name
+	}
+	b.WriteString(ui.Separator(label))
+	b.WriteString("\n")
+	b.WriteString(d.log.View())
+	b.WriteString("\n")
+	if d.saying {
+		b.WriteString(d.input.View())
+		b.WriteString("\n")
+	}
+	if d.flash != "" {
+		b.WriteString(flashStyle.Render(d.flash))
+		b.WriteString("  ")
+	}
+	b.WriteString(helpStyle.Render("↑/↓ select · p pause/resume · s stop · m message agent · o open worktree · pgup/pgdn scroll · q quit"))
+	return b.String()
+}
+
+func (d *dashboard) row(s *session.State, selected bool) string {
+	status := s.Status
+	if status == "running" && s.Paused {
+		status = "paused"
+	}
+	progress := "-"
+	if s.TasksTotal > 0 {
+		progress = fmt.Sprintf("%d/%d (%d%%)", s.TasksDone, s.TasksTotal, s.TasksDone*100/s.TasksTotal)
+	}
+	tests := s.LastTests
+	if tests == "" {
+		tests = "-"
+	}
+	workDir := s.WorkDir
+	if d.width > 0 {
+		used := len(columns)
+		for _, w := range columns {
+			used += w
+		}
+		workDir = truncate(workDir, max(d.width-used, 1))
+	}
+	cells := []string{
+		s.Name,
+		status,
+		fmt.Sprintf("%d/%d", s.Iterations, s.MaxIterations),
+		ui.FormatDuration(elapsed(s)),
+		progress,
+		tests,
+		workDir,
+	}
+	if selected {
+		return selectedStyle.Render(formatRow(cells, nil))
+	}
+	return formatRow(cells, map[int]func(string) string{1: ui.FormatStatus, 5: colorTests})
+}
+
+// formatRow pads cells to the column widths, applying any per-column color
+// after padding is measured so escape codes don't skew alignment.
+func formatRow(cells []string, colors map[int]func(string) string) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		if i < len(columns) {
+			cell = truncate(cell, columns[i])
+		}
+		text := cell
+		if color, ok := colors[i]; ok {
+			text = color(cell)
+		}
+		b.WriteString(text)
+		if i < len(columns) {
+			b.WriteString(strings.Repeat(" ", columns[i]-len([]rune(cell))+1))
+		}
+	}
+	return b.String()
+}
+
+func colorTests(tests string) string {
+	switch tests {
+	case "passed":
+		return passStyle.Render(tests)
+	case "failed":
+		return failStyle.Render(tests)
+	default:
+		return tests
+	}
+}
+
+func elapsed(s *session.State) time.Duration {
+	if s.EndTime != nil {
+		return s.EndTime.Sub(s.StartTime)
+	}
+	return time.Since(s.StartTime)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/ui"
+)
+
+const (
+	// initialLogBytes is how much of an existing log is loaded when a
+	// session is first selected.
+	initialLogBytes = 64 * 1024
+	// maxLogLines bounds the lines kept in memory for the log pane.
+	maxLogLines = 2000
+)
+
+// logReader incrementally reads a session log for display, replacing
+// section markers with separators.
+type logReader struct {
+	path      string
+	offset    int64
+	partial   string
+	lines     []logLine
+	section   string
+	iteration int
+}
+
+// logLine is one displayable log line and the part of the loop it came from.
+type logLine struct {
+	text      string
+	section   string
+	iteration int
+}
+
+// read appends any new output and reports whether there was some.
+func (r *logReader) read() bool {
+	if r.path == "" {
+		return false
+	}
+	f, err := os.Open(r.path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if info.Size() < r.offset {
+		// The log was replaced; start over.
+		*r = logReader{path: r.path}
+	}
+	if r.offset == 0 && info.Size() > initialLogBytes {
+		r.offset = info.Size() - initialLogBytes
+	}
+	if info.Size() == r.offset {
+		return false
+	}
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		return false
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || len(data) == 0 {
+		return false
+	}
+	r.offset += int64(len(data))
+
+	text := r.partial + string(data)
+	parts := strings.Split(text, "\n")
+	r.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		if section, iteration, ok := session.ParseMarker(line); ok {
+			r.section, r.iteration = section, iteration
+		} else if r.section == "" {
+			r.section = session.SectionAgent
+		}
+		r.lines = append(r.lines, logLine{text: displayLine(line), section: r.section, iteration: r.iteration})
+	}
+	if len(r.lines) > maxLogLines {
+		r.lines = r.lines[len(r.lines)-maxLogLines:]
+	}
+	return true
+}
+
+// displayLine turns a section marker into a separator and passes other
+// lines through.
+func displayLine(line string) string {
+	section, iteration, ok := session.ParseMarker(line)
+	if !ok {
+		return line
+	}
+	if section == session.SectionTests {
+		return ui.Separator(fmt.Sprintf("tests · iteration %d", iteration))
+	}
+	return ui.Separator(fmt.Sprintf("iteration %d", iteration))
+}
+
+// text joins the lines that satisfy keep, or all lines if keep is nil.
+func (r *logReader) text(keep func(logLine) bool) string {
+	var b strings.Builder
+	for _, l := range r.lines {
+		if keep != nil && !keep(l) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(l.text)
+	}
+	return b.String()
+}
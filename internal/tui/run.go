@@ -0,0 +1,294 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kfroemming/ralphkit/internal/session"
+	"github.com/kfroemming/ralphkit/internal/ui"
+)
+
+// loopRefreshInterval is how often the run view picks up new output.
+const loopRefreshInterval = 250 * time.Millisecond
+
+// RunLoop shows a live split view of a loop while run executes it. run must
+// call onStart with the session name once the session is registered.
+// Interrupting the view cancels the loop through cancel. RunLoop returns
+// once the loop has finished, with the session's final state.
+func RunLoop(cancel context.CancelFunc, run func(onStart func(name string)) error) (*session.State, error) {
+	v := newLoopView(cancel)
+	p := tea.NewProgram(v, tea.WithAltScreen())
+	errCh := make(chan error, 1)
+	go func() {
+		err := run(func(name string) { p.Send(loopStartedMsg(name)) })
+		errCh <- err
+		p.Send(loopDoneMsg{err})
+	}()
+	if _, err := p.Run(); err != nil {
+		cancel()
+		<-errCh
+		return nil, err
+	}
+	err := <-errCh
+	if v.name == "" {
+		return nil, err
+	}
+	s, loadErr := session.Load(v.name)
+	if loadErr != nil {
+		return nil, err
+	}
+	return s, err
+}
+
+type loopStartedMsg string
+
+type loopDoneMsg struct{ err error }
+
+type loopTickMsg time.Time
+
+type loopView struct {
+	cancel context.CancelFunc
+	name   string
+	state  *session.State
+	log    logReader
+
+	agent  viewport.Model
+	tests  viewport.Model
+	bar    progress.Model
+	input  textinput.Model
+	saying bool
+	flash  string
+
+	stopping bool
+	done     bool
+	err      error
+
+	width, height int
+}
+
+func newLoopView(cancel context.CancelFunc) *loopView {
+	in := textinput.New()
+	in.Placeholder = "guidance for the agent's next iteration"
+	in.Prompt = "say> "
+	return &loopView{
+		cancel: cancel,
+		agent:  viewport.New(80, 10),
+		tests:  viewport.New(80, 5),
+		bar:    progress.New(progress.WithDefaultGradient(), progress.WithoutPercentage()),
+		input:  in,
+	}
+}
+
+func loopTick() tea.Cmd {
+	return tea.Tick(loopRefreshInterval, func(t time.Time) tea.Msg { return loopTickMsg(t) })
+}
+
+func (v *loopView) Init() tea.Cmd {
+	return loopTick()
+}
+
+func (v *loopView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		v.width, v.height = msg.Width, msg.Height
+		v.layout()
+		return v, nil
+
+	case loopStartedMsg:
+		v.name = string(msg)
+		return v, nil
+
+	case loopTickMsg:
+		v.refresh()
+		return v, loopTick()
+
+	case loopDoneMsg:
+		v.refresh()
+		v.done = true
+		v.err = msg.err
+		return v, nil
+
+	case tea.KeyMsg:
+		if v.saying {
+			return v.updateSay(msg)
+		}
+		return v.updateKeys(msg)
+	}
+	return v, nil
+}
+
+func (v *loopView) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if v.done {
+		switch msg.String() {
+		case "q", "ctrl+c", "esc", "enter":
+			return v, tea.Quit
+		}
+	}
+	switch msg.String() {
+	case "ctrl+c", "q":
+		v.stopping = true
+		v.flash = "Interrupting..."
+		v.cancel()
+	case "p":
+		if v.name != "" {
+			v.flash = togglePause(&session.State{Name: v.name})
+		}
+	case "s":
+		if v.name != "" {
+			if err := session.RequestStop(v.name); err != nil {
+				v.flash = err.Error()
+			} else {
+				v.flash = "Will stop after the current iteration."
+			}
+		}
+	case "m":
+		if v.name != "" {
+			v.saying = true
+			v.input.Reset()
+			v.input.Focus()
+			v.layout()
+			return v, textinput.Blink
+		}
+	default:
+		var cmd tea.Cmd
+		v.agent, cmd = v.agent.Update(msg)
+		return v, cmd
+	}
+	return v, nil
+}
+
+func (v *loopView) updateSay(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		v.saying = false
+		v.input.Blur()
+		v.layout()
+		return v, nil
+	case "enter":
+		v.saying = false
+		v.input.Blur()
+		v.layout()
+		if err := session.Say(v.name, v.input.Value()); err != nil {
+			v.flash = err.Error()
+		} else {
+			v.flash = "Queued note for the next iteration."
+		}
+		return v, nil
+	}
+	var cmd tea.Cmd
+	v.input, cmd = v.input.Update(msg)
+	return v, cmd
+}
+
+// refresh reloads the session state and routes new log output to the agent
+// pane and, for the latest test run, the tests pane.
+func (v *loopView) refresh() {
+	if v.name == "" {
+		return
+	}
+	if s, err := session.Load(v.name); err == nil {
+		v.state = s
+		if v.log.path == "" {
+			v.log.path = s.LogFile
+		}
+	}
+	if !v.log.read() {
+		return
+	}
+	agentAtBottom := v.agent.AtBottom() || v.agent.TotalLineCount() == 0
+	v.agent.SetContent(v.log.text(func(l logLine) bool { return l.section == session.SectionAgent }))
+	if agentAtBottom {
+		v.agent.GotoBottom()
+	}
+	latest := 0
+	for _, l := range v.log.lines {
+		if l.section == session.SectionTests {
+			latest = l.iteration
+		}
+	}
+	v.tests.SetContent(v.log.text(func(l logLine) bool {
+		return l.section == session.SectionTests && l.iteration == latest
+	}))
+	v.tests.GotoBottom()
+}
+
+func (v *loopView) layout() {
+	if v.width == 0 {
+		return
+	}
+	// Header (2 lines), two pane titles, footer and the optional say prompt.
+	chrome := 5
+	if v.saying {
+		chrome++
+	}
+	avail := max(v.height-chrome, 6)
+	v.tests.Height = max(avail/3, 3)
+	v.agent.Height = avail - v.tests.Height
+	v.agent.Width = v.width
+	v.tests.Width = v.width
+	v.bar.Width = min(max(v.width-30, 10), 60)
+	v.input.Width = v.width - len(v.input.Prompt) - 1
+}
+
+func (v *loopView) View() string {
+	var b strings.Builder
+	b.WriteString(v.header())
+	b.WriteString("\n")
+	b.WriteString(ui.Separator("agent"))
+	b.WriteString("\n")
+	b.WriteString(v.agent.View())
+	b.WriteString("\n")
+	testsLabel := "tests"
+	if v.state != nil && v.state.LastTests != "" {
+		testsLabel = "tests: " + v.state.LastTests
+	}
+	b.WriteString(ui.Separator(testsLabel))
+	b.WriteString("\n")
+	b.WriteString(v.tests.View())
+	b.WriteString("\n")
+	if v.saying {
+		b.WriteString(v.input.View())
+		b.WriteString("\n")
+	}
+	if v.flash != "" {
+		b.WriteString(flashStyle.Render(v.flash))
+		b.WriteString("  ")
+	}
+	switch {
+	case v.done:
+		b.WriteString(helpStyle.Render("Loop finished · q quit"))
+	case v.stopping:
+		b.WriteString(helpStyle.Render("Waiting for the loop to stop..."))
+	default:
+		b.WriteString(helpStyle.Render("p pause/resume · s stop after iteration · m message agent · pgup/pgdn scroll · q interrupt"))
+	}
+	return b.String()
+}
+
+// header renders the session, iteration, elapsed time and PRD progress.
+func (v *loopView) header() string {
+	s := v.state
+	if s == nil {
+		return titleStyle.Render("Ralph Loop — starting...") + "\n"
+	}
+	status := s.Status
+	if status == "running" && s.Paused {
+		status = "paused"
+	}
+	title := fmt.Sprintf("Ralph Loop — %s · iteration %d/%d · %s · ",
+		s.Name, s.Iterations, s.MaxIterations, ui.FormatDuration(elapsed(s)))
+	line := titleStyle.Render(title) + ui.FormatStatus(status)
+
+	progressLine := helpStyle.Render("No checklist items in PRD")
+	if s.TasksTotal > 0 {
+		pct := float64(s.TasksDone) / float64(s.TasksTotal)
+		progressLine = fmt.Sprintf("%s %d/%d items (%d%%)", v.bar.ViewAs(pct), s.TasksDone, s.TasksTotal, s.TasksDone*100/s.TasksTotal)
+	}
+	return line + "\n" + progressLine
+}
@@ -6,13 +6,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aymanbagabas/go-udiff"
 	"github.com/charmbracelet/lipgloss"
 )
 
 var (
-	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10")) // green
-	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")) // yellow
-	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))  // red
+	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))            // green
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))            // yellow
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))             // red
 	headerStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14")) // cyan bold
 	dimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))             // dim
 )
@@ -20,47 +21,57 @@ var (
 // Quiet suppresses UI chrome when true.
 var Quiet bool
 
+// Silent suppresses all terminal output, for when a full-screen view owns
+// the terminal.
+var Silent bool
+
 func Success(msg string) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
 	fmt.Fprintln(os.Stderr, successStyle.Render(msg))
 }
 
 func Warn(msg string) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
 	fmt.Fprintln(os.Stderr, warningStyle.Render(msg))
 }
 
 func Error(msg string) {
+	if Silent {
+		return
+	}
 	fmt.Fprintln(os.Stderr, errorStyle.Render(msg))
 }
 
 func Header(msg string) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
 	fmt.Fprintln(os.Stderr, headerStyle.Render(msg))
 }
 
 func Dim(msg string) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
 	fmt.Fprintln(os.Stderr, dimStyle.Render(msg))
 }
 
 func IterationHeader(current, max int, elapsed time.Duration) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
-	line := fmt.Sprintf("=== Iteration %d/%d === [elapsed: %s]", current, max, formatDuration(elapsed))
+	line := fmt.Sprintf("=== Iteration %d/%d === [elapsed: %s]", current, max, FormatDuration(elapsed))
 	fmt.Fprintln(os.Stderr, headerStyle.Render(line))
 }
 
 func Celebration(iterations int, elapsed time.Duration) {
+	if Silent {
+		return
+	}
 	if Quiet {
 		fmt.Println("ALL_DONE")
 		return
@@ -74,13 +85,13 @@ func Celebration(iterations int, elapsed time.Duration) {
 		"%s\n\n%s\n%s",
 		successStyle.Render("Ralph loop complete!"),
 		fmt.Sprintf("Iterations: %d", iterations),
-		fmt.Sprintf("Total time: %s", formatDuration(elapsed)),
+		fmt.Sprintf("Total time: %s", FormatDuration(elapsed)),
 	)
 	fmt.Fprintln(os.Stderr, box.Render(content))
 }
 
 func MaxIterationsWarning(max int) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
 	msg := fmt.Sprintf("Reached max iterations (%d). PRD may not be fully complete.\nRe-run with --max-iterations %d to continue.", max, max*2)
@@ -88,13 +99,16 @@ func MaxIterationsWarning(max int) {
 }
 
 func StatusLine(label, value string) {
-	if Quiet {
+	if Quiet || Silent {
 		return
 	}
 	fmt.Fprintf(os.Stderr, "  %s %s\n", dimStyle.Render(label+":"), value)
 }
 
 func PrintLastLines(output string, n int) {
+	if Silent {
+		return
+	}
 	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
 	start := 0
 	if len(lines) > n {
@@ -105,6 +119,46 @@ func PrintLastLines(output string, n int) {
 	}
 }
 
+// PrintDiff prints a colored unified diff between two versions of a text.
+func PrintDiff(oldLabel, newLabel, old, new string) {
+	if Silent {
+		return
+	}
+	diff := udiff.Unified(oldLabel, newLabel, old, new)
+	if diff == "" {
+		Dim("No changes.")
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = headerStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			line = dimStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			line = successStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = errorStyle.Render(line)
+		}
+		fmt.Println(line)
+	}
+}
+
+// Separator returns a styled divider line with a label, for use in log output.
+func Separator(label string) string {
+	return headerStyle.Render(fmt.Sprintf("──── %s ────", label))
+}
+
+// prefixColors cycles through distinguishable colors for session prefixes.
+var prefixColors = []string{"14", "13", "11", "10", "12", "9", "6", "5", "3", "2"}
+
+// SessionPrefix returns a colored "[name]" prefix for interleaved output.
+// Sessions with different indexes get different colors.
+func SessionPrefix(name string, index int) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(prefixColors[index%len(prefixColors)]))
+	return style.Render("[" + name + "]")
+}
+
 // FormatStatus returns a color-coded status string.
 func FormatStatus(status string) string {
 	switch status {
@@ -114,12 +168,17 @@ func FormatStatus(status string) string {
 		return successStyle.Render("complete")
 	case "stopped":
 		return warningStyle.Render("stopped")
+	case "crashed":
+		return errorStyle.Render("crashed")
+	case "paused":
+		return warningStyle.Render("paused")
 	default:
 		return status
 	}
 }
 
-func formatDuration(d time.Duration) string {
+// FormatDuration renders a duration compactly, e.g. 42.0s, 3m5s or 1h2m3s.
+func FormatDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%.1fs", d.Seconds())
 	}